@@ -6,7 +6,11 @@ import (
 	"time"
 
 	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
 )
 
 func newContext() (context.Context, context.CancelFunc) {
@@ -17,6 +21,8 @@ func newContext() (context.Context, context.CancelFunc) {
 // Account define binance account
 type Account struct {
 	*binance.Client
+	Futures  *futures.Client   `json:"-"`
+	Delivery *delivery.Client  `json:"-"`
 	Name     string            `json:"name"`
 	Balances []binance.Balance `json:"balances"`
 }
@@ -85,25 +91,29 @@ func (account *Account) ListPrices(symbol string) ([]*binance.SymbolPrice, error
 }
 
 // CancelOrder cancel open order
-func (account *Account) CancelOrder(symbol string, orderID int64) error {
+func (account *Account) CancelOrder(symbol string, orderID int64) (*binance.CancelOrderResponse, error) {
 	ctx, cancel := newContext()
 	defer cancel()
-	_, err := account.NewCancelOrderService().Symbol(symbol).OrderID(orderID).Do(ctx)
+	res, err := account.NewCancelOrderService().Symbol(symbol).OrderID(orderID).Do(ctx)
 	if err != nil {
-		return errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
-	return nil
+	return res, nil
 }
 
 // CreateOrder create order
-func (account *Account) CreateOrder(symbol, side, quantity, price string) (*binance.CreateOrderResponse, error) {
+func (account *Account) CreateOrder(symbol, side, quantity, price, clientOrderID string) (*binance.CreateOrderResponse, error) {
 	ctx, cancel := newContext()
 	defer cancel()
 	side = strings.ToUpper(side)
 	sideType := binance.SideType(side)
-	res, err := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
+	service := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
 		Quantity(quantity).Price(price).Type(binance.OrderTypeLimit).
-		TimeInForce(binance.TimeInForceTypeGTC).Do(ctx)
+		TimeInForce(binance.TimeInForceTypeGTC)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	res, err := service.Do(ctx)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -111,20 +121,85 @@ func (account *Account) CreateOrder(symbol, side, quantity, price string) (*bina
 }
 
 // TestCreateOrder create order for test
-func (account *Account) TestCreateOrder(symbol, side, quantity, price string) error {
+func (account *Account) TestCreateOrder(symbol, side, quantity, price, clientOrderID string) error {
 	ctx, cancel := newContext()
 	defer cancel()
 	side = strings.ToUpper(side)
 	sideType := binance.SideType(side)
-	err := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
+	service := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
 		Quantity(quantity).Price(price).Type(binance.OrderTypeLimit).
-		TimeInForce(binance.TimeInForceTypeGTC).Test(ctx)
+		TimeInForce(binance.TimeInForceTypeGTC)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	err := service.Test(ctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	return nil
 }
 
+// CreateMarketOrder create MARKET order
+func (account *Account) CreateMarketOrder(symbol, side, quantity, clientOrderID string) (*binance.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	side = strings.ToUpper(side)
+	sideType := binance.SideType(side)
+	service := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
+		Quantity(quantity).Type(binance.OrderTypeMarket)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// CreateIOCOrder create LIMIT order canceled immediately if not filled
+func (account *Account) CreateIOCOrder(symbol, side, quantity, price, clientOrderID string) (*binance.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	side = strings.ToUpper(side)
+	sideType := binance.SideType(side)
+	service := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
+		Quantity(quantity).Price(price).Type(binance.OrderTypeLimit).
+		TimeInForce(binance.TimeInForceTypeIOC)
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// GetOrder get order by order id or client order id
+func (account *Account) GetOrder(symbol string, orderID int64, clientOrderID string) (*binance.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewGetOrderService().Symbol(symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	}
+	if clientOrderID != "" {
+		service = service.OrigClientOrderID(clientOrderID)
+	}
+	order, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return order, nil
+}
+
+// isOrderNotExist check if err means the queried order does not exist
+func isOrderNotExist(err error) bool {
+	apiErr, ok := errors.Cause(err).(*common.APIError)
+	return ok && apiErr.Code == -2013
+}
+
 // ListSymbols list symbols
 func (account *Account) ListSymbols() (map[string]binance.Symbol, error) {
 	ctx, cancel := newContext()
@@ -170,3 +245,399 @@ func (account *Account) GetMarginAccount() (*binance.MarginAccount, error) {
 	}
 	return marginAccount, nil
 }
+
+// CancelOpenOrders cancel all open orders of symbol
+func (account *Account) CancelOpenOrders(symbol string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	_, err := account.NewCancelOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ListMarginOpenOrders list margin open orders
+func (account *Account) ListMarginOpenOrders(symbol string) ([]*binance.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListMarginOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orders, nil
+}
+
+// GetMarginOrder get margin order by order id or client order id
+func (account *Account) GetMarginOrder(symbol string, orderID int64, clientOrderID string) (*binance.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewGetMarginOrderService().Symbol(symbol)
+	if orderID != 0 {
+		service = service.OrderID(orderID)
+	}
+	if clientOrderID != "" {
+		service = service.OrigClientOrderID(clientOrderID)
+	}
+	order, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return order, nil
+}
+
+// CancelMarginOrder cancel margin open order
+func (account *Account) CancelMarginOrder(symbol string, orderID int64) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	_, err := account.NewCancelMarginOrderService().Symbol(symbol).OrderID(orderID).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// MarginRepay repay margin loan of asset
+func (account *Account) MarginRepay(asset, amount string) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewMarginRepayService().Asset(asset).Amount(amount).Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.TranID, nil
+}
+
+// ListBookTickers list best price and quantity on the order book
+func (account *Account) ListBookTickers(symbol string) ([]*binance.BookTicker, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListBookTickersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	tickers, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tickers, nil
+}
+
+// MidPrice return mid price of best bid and ask of symbol
+func (account *Account) MidPrice(symbol string) (decimal.Decimal, error) {
+	tickers, err := account.ListBookTickers(symbol)
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	for _, ticker := range tickers {
+		if ticker.Symbol == symbol {
+			bid := decimal.RequireFromString(ticker.BidPrice)
+			ask := decimal.RequireFromString(ticker.AskPrice)
+			return bid.Add(ask).Div(decimal.NewFromInt(2)), nil
+		}
+	}
+	return decimal.Decimal{}, errors.Errorf("book ticker of %s not found", symbol)
+}
+
+// CommissionRate return the higher of maker and taker commission rate
+func (account *Account) CommissionRate() (decimal.Decimal, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	commission := res.MakerCommission
+	if res.TakerCommission > commission {
+		commission = res.TakerCommission
+	}
+	// commission is in units of 0.01%
+	return decimal.New(commission, -4), nil
+}
+
+// AveragePrice return current average price of symbol
+func (account *Account) AveragePrice(symbol string) (string, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewAveragePriceService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return res.Price, nil
+}
+
+// CreateTypedOrder create order of type with time in force, price and time in
+// force are ignored by MARKET order, response is nil for test order
+func (account *Account) CreateTypedOrder(symbol, side, orderType, timeInForce, quantity, price, clientOrderID string,
+	isTest bool) (*binance.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	side = strings.ToUpper(side)
+	sideType := binance.SideType(side)
+	service := account.NewCreateOrderService().Symbol(symbol).Side(sideType).
+		Quantity(quantity).Type(binance.OrderType(orderType))
+	if binance.OrderType(orderType) != binance.OrderTypeMarket {
+		service = service.Price(price).TimeInForce(binance.TimeInForceType(timeInForce))
+	}
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	if isTest {
+		return nil, errors.Trace(service.Test(ctx))
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// MarginBorrow apply for margin loan of asset
+func (account *Account) MarginBorrow(asset, amount string) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewMarginLoanService().Asset(asset).Amount(amount).Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.TranID, nil
+}
+
+// GetMaxBorrowable get max borrowable amount of asset
+func (account *Account) GetMaxBorrowable(asset string) (string, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewGetMaxBorrowableService().Asset(asset).Do(ctx)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return res.Amount, nil
+}
+
+// ListMarginLoans list margin loan records of asset
+func (account *Account) ListMarginLoans(asset string, limit int) ([]binance.MarginLoan, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListMarginLoansService().Asset(asset)
+	if limit > 0 {
+		service = service.Size(int64(limit))
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res.Rows, nil
+}
+
+// ListMarginRepays list margin repay records of asset
+func (account *Account) ListMarginRepays(asset string, limit int) ([]binance.MarginRepay, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListMarginRepaysService().Asset(asset)
+	if limit > 0 {
+		service = service.Size(int64(limit))
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res.Rows, nil
+}
+
+// GetMaxTransferable get max amount of asset transferable out of margin account
+func (account *Account) GetMaxTransferable(asset string) (string, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewGetMaxTransferableService().Asset(asset).Do(ctx)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return res.Amount, nil
+}
+
+// MarginTransfer transfer asset between spot and margin account
+func (account *Account) MarginTransfer(asset, amount string, transferType binance.MarginTransferType) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewMarginTransferService().Asset(asset).Amount(amount).Type(transferType).Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.TranID, nil
+}
+
+// FuturesTransfer transfer asset between spot and USDT-M or COIN-M futures account
+func (account *Account) FuturesTransfer(asset, amount string, transferType binance.FuturesTransferType) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.NewFuturesTransferService().Asset(asset).Amount(amount).Type(transferType).Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.TranID, nil
+}
+
+// ListFuturesTransfers list transfers between spot and futures accounts of asset
+func (account *Account) ListFuturesTransfers(asset string, startTime int64, limit int) ([]binance.FuturesTransfer, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListFuturesTransferService().Asset(asset).StartTime(startTime)
+	if limit > 0 {
+		service = service.Size(int64(limit))
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res.Rows, nil
+}
+
+// CreateMarginOrder create LIMIT margin order with side effect
+func (account *Account) CreateMarginOrder(symbol, side, quantity, price, sideEffect,
+	clientOrderID string) (*binance.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	side = strings.ToUpper(side)
+	sideType := binance.SideType(side)
+	service := account.NewCreateMarginOrderService().Symbol(symbol).Side(sideType).
+		Quantity(quantity).Price(price).Type(binance.OrderTypeLimit).
+		TimeInForce(binance.TimeInForceTypeGTC).SideEffectType(binance.SideEffectType(sideEffect))
+	if clientOrderID != "" {
+		service = service.NewClientOrderID(clientOrderID)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// ListMarginOrders list all margin orders of symbol
+func (account *Account) ListMarginOrders(symbol string, limit int) ([]*binance.MarginAllOrder, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListMarginOrdersService().Symbol(symbol)
+	if limit > 0 {
+		service = service.Limit(limit)
+	}
+	orders, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orders, nil
+}
+
+// ListMarginTrades list margin trades of symbol
+func (account *Account) ListMarginTrades(symbol string, limit int) ([]*binance.TradeV3, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.NewListMarginTradesService().Symbol(symbol)
+	if limit > 0 {
+		service = service.Limit(limit)
+	}
+	trades, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return trades, nil
+}
+
+// CreateMarginMarketOrder create MARKET margin order with side effect
+func (account *Account) CreateMarginMarketOrder(symbol, side, quantity, sideEffect string) (*binance.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	side = strings.ToUpper(side)
+	sideType := binance.SideType(side)
+	res, err := account.NewCreateMarginOrderService().Symbol(symbol).Side(sideType).
+		Quantity(quantity).Type(binance.OrderTypeMarket).
+		SideEffectType(binance.SideEffectType(sideEffect)).Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// maxMarginHistoryWindow is the max time range of one margin history request
+const maxMarginHistoryWindow = int64(30 * 24 * time.Hour / time.Millisecond)
+
+// marginHistoryPageSize is the max number of records of one margin history page
+const marginHistoryPageSize = 100
+
+// ListMarginLoansBetween list confirmed margin loan records of asset between
+// start and end time in milliseconds
+func (account *Account) ListMarginLoansBetween(asset string, startTime, endTime int64) ([]binance.MarginLoan, error) {
+	var loans []binance.MarginLoan
+	for windowStart := startTime; windowStart < endTime; windowStart += maxMarginHistoryWindow {
+		windowEnd := windowStart + maxMarginHistoryWindow
+		if windowEnd > endTime {
+			windowEnd = endTime
+		}
+		for current := int64(1); ; current++ {
+			ctx, cancel := newContext()
+			res, err := account.NewListMarginLoansService().Asset(asset).StartTime(windowStart).EndTime(windowEnd).
+				Current(current).Size(marginHistoryPageSize).Do(ctx)
+			cancel()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			for _, loan := range res.Rows {
+				if loan.Status == binance.MarginLoanStatusTypeConfirmed {
+					loans = append(loans, loan)
+				}
+			}
+			if len(res.Rows) < marginHistoryPageSize {
+				break
+			}
+		}
+	}
+	return loans, nil
+}
+
+// ListMarginRepaysBetween list confirmed margin repay records of asset
+// between start and end time in milliseconds
+func (account *Account) ListMarginRepaysBetween(asset string, startTime, endTime int64) ([]binance.MarginRepay, error) {
+	var repays []binance.MarginRepay
+	for windowStart := startTime; windowStart < endTime; windowStart += maxMarginHistoryWindow {
+		windowEnd := windowStart + maxMarginHistoryWindow
+		if windowEnd > endTime {
+			windowEnd = endTime
+		}
+		for current := int64(1); ; current++ {
+			ctx, cancel := newContext()
+			res, err := account.NewListMarginRepaysService().Asset(asset).StartTime(windowStart).EndTime(windowEnd).
+				Current(current).Size(marginHistoryPageSize).Do(ctx)
+			cancel()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			for _, repay := range res.Rows {
+				if repay.Status == binance.MarginRepayStatusTypeConfirmed {
+					repays = append(repays, repay)
+				}
+			}
+			if len(res.Rows) < marginHistoryPageSize {
+				break
+			}
+		}
+	}
+	return repays, nil
+}
+
+// HistoricalPrice return open price of the hourly kline of symbol at time
+// in milliseconds
+func (account *Account) HistoricalPrice(symbol string, at int64) (string, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	klines, err := account.NewKlinesService().Symbol(symbol).Interval("1h").StartTime(at).Limit(1).Do(ctx)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(klines) == 0 {
+		return "", errors.Errorf("kline of %s at %d not found", symbol, at)
+	}
+	return klines[0].Open, nil
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	binance "github.com/adshao/go-binance/v2"
 	"github.com/juju/errors"
@@ -40,7 +44,7 @@ func listBalances(c *cli.Context) error {
 				l = append(l, b)
 			}
 		}
-		return &AccountConfig{account.Name, l}, nil
+		return &AccountConfig{Name: account.Name, Balances: l}, nil
 	}, func(results map[string]interface{}) (interface{}, error) {
 		if !total {
 			return results, nil
@@ -73,6 +77,7 @@ func listOrders(c *cli.Context) error {
 	symbol := c.String("symbol")
 	all := c.Bool("all")
 	limit := c.Int("limit")
+	clientIDPrefix := c.String("client-id-prefix")
 	return accountsDo(func(account *Account) (interface{}, error) {
 		var orders []*binance.Order
 		var err error
@@ -87,7 +92,8 @@ func listOrders(c *cli.Context) error {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		return orders, nil
+		filter := &OrderFilter{ClientIDPrefix: clientIDPrefix}
+		return filter.Filter(orders, time.Now()), nil
 	})
 }
 
@@ -103,29 +109,114 @@ func listPrices(c *cli.Context) error {
 }
 
 func cancelOrders(c *cli.Context) error {
+	return runCancelOrders(c, "orders",
+		func(account *Account, symbol string) ([]*binance.Order, error) {
+			return account.ListOpenOrders(symbol)
+		},
+		func(account *Account, symbol string, orderID int64) error {
+			_, err := account.CancelOrder(symbol, orderID)
+			return errors.Trace(err)
+		})
+}
+
+// runCancelOrders select open orders of accounts by the order id or filter
+// flags, then cancel them after confirmation. orders of symbols not allowed by
+// limits are left untouched
+func runCancelOrders(c *cli.Context, description string,
+	listOpenOrders func(*Account, string) ([]*binance.Order, error),
+	cancelOrder func(*Account, string, int64) error) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	symbol := c.String("symbol")
 	orderID := c.Int64("id")
-	return accountsDo(
+	keepBest := c.Int("keep-best")
+	isDryRun := c.Bool("dry-run")
+	filter := &OrderFilter{
+		Side:           c.String("side"),
+		Type:           c.String("type"),
+		PriceAbove:     c.String("price-above"),
+		PriceBelow:     c.String("price-below"),
+		OlderThan:      c.Duration("older-than"),
+		ClientIDPrefix: c.String("client-id-prefix"),
+	}
+	if err := filter.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if orderID != 0 && (!filter.IsEmpty() || keepBest > 0) {
+		return errors.New("filters and --keep-best are not allowed with order id")
+	}
+	if err := checkOrderID(symbol, orderID, name); err != nil {
+		return errors.Trace(err)
+	}
+	if symbol != "" {
+		if err := config.CheckSymbol(symbol); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	results := accountsRun(
 		func(account *Account) (interface{}, error) {
-			var canceledOrders []int64
-			var cancelingOrders []int64
-			if orderID == 0 {
-				orders, err := account.ListOpenOrders(symbol)
-				if err != nil {
-					return nil, errors.Trace(err)
+			if orderID != 0 {
+				return []*binance.Order{{Symbol: symbol, OrderID: orderID}}, nil
+			}
+			orders, err := listOpenOrders(account, symbol)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			var cancelingOrders []*binance.Order
+			for _, order := range filter.Filter(orders, time.Now()) {
+				if config.CheckSymbol(order.Symbol) == nil {
+					cancelingOrders = append(cancelingOrders, order)
 				}
-				for _, order := range orders {
-					cancelingOrders = append(cancelingOrders, order.OrderID)
+			}
+			if keepBest > 0 {
+				mids := make(map[string]decimal.Decimal)
+				for _, order := range cancelingOrders {
+					if _, ok := mids[order.Symbol]; ok {
+						continue
+					}
+					mid, err := account.MidPrice(order.Symbol)
+					if err != nil {
+						return nil, errors.Trace(err)
+					}
+					mids[order.Symbol] = mid
 				}
-			} else {
-				cancelingOrders = []int64{orderID}
+				cancelingOrders = KeepBest(cancelingOrders, keepBest, mids)
+			}
+			return cancelingOrders, nil
+		})
+	if isDryRun {
+		return print(results)
+	}
+	var numOrders, numAccounts int
+	for _, res := range results {
+		if orders, ok := res.([]*binance.Order); ok && len(orders) > 0 {
+			numOrders += len(orders)
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numOrders > 0 {
+		if err := confirm("cancel %d %s on %d accounts?", numOrders, description, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			cancelingOrders, ok := results[account.Name].([]*binance.Order)
+			if !ok {
+				return nil, resultError(results[account.Name])
 			}
-			for _, orderID := range cancelingOrders {
-				err := account.CancelOrder(symbol, orderID)
+			var canceledOrders []int64
+			for _, order := range cancelingOrders {
+				err := cancelOrder(account, order.Symbol, order.OrderID)
 				if err != nil {
 					return nil, errors.Trace(err)
 				}
-				canceledOrders = append(canceledOrders, orderID)
+				canceledOrders = append(canceledOrders, order.OrderID)
 			}
 			return canceledOrders, nil
 		})
@@ -142,7 +233,151 @@ func (account *Account) loadSymbols() error {
 	return nil
 }
 
+// freeBalance return free balance of asset excluding configured locked amount
+func (account *Account) freeBalance(asset string, accountBalances map[string]map[string]binance.Balance) (decimal.Decimal, error) {
+	balances, err := account.ListBalances()
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	balance, ok := balances[asset]
+	if !ok {
+		return decimal.Decimal{}, errors.Errorf("balance %s not found", asset)
+	}
+	amount := decimal.RequireFromString(balance.Free)
+	if balanceMap, ok := accountBalances[account.Name]; ok {
+		if b, ok := balanceMap[asset]; ok {
+			if b.Locked != "" {
+				amount = amount.Sub(decimal.RequireFromString(b.Locked))
+			}
+		}
+	}
+	return amount, nil
+}
+
+// orderQuantity resolve percent quantity to lot size quantity of symbol
+func (account *Account) orderQuantity(symbol, side, quantity, price string,
+	accountBalances map[string]map[string]binance.Balance) (string, error) {
+	newQuantity, _, err := account.sizeOrder(symbol, side, quantity, "", price, accountBalances)
+	return newQuantity, errors.Trace(err)
+}
+
+// sizeOrder resolve quantity to lot size quantity of symbol, it also returns
+// how the quantity is computed. Supported quantity are absolute, percent of
+// free balance "10%", percent of total equity in quote asset "10%equity" and
+// "max" which is free balance less commission. quoteAmount overrides
+// quantity with the amount of quote asset to spend or receive
+func (account *Account) sizeOrder(symbol, side, quantity, quoteAmount, price string,
+	accountBalances map[string]map[string]binance.Balance) (string, string, error) {
+	if quoteAmount == "" && quantity != "max" && !strings.HasSuffix(quantity, "%") &&
+		!strings.HasSuffix(quantity, "%equity") {
+		return quantity, "", nil
+	}
+	p, err := decimal.NewFromString(price)
+	if err != nil || !p.IsPositive() {
+		return "", "", errors.Errorf("invalid price %q", price)
+	}
+	var quote decimal.Decimal
+	if quoteAmount != "" {
+		quote, err = decimal.NewFromString(quoteAmount)
+		if err != nil || !quote.IsPositive() {
+			return "", "", errors.Errorf("invalid quote amount %q", quoteAmount)
+		}
+	}
+	err = account.loadSymbols()
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return "", "", errors.Errorf("symbol %s not found", symbol)
+	}
+	precision := int32(info.BaseAssetPrecision)
+
+	var amount decimal.Decimal
+	var sizing string
+	switch {
+	case quoteAmount != "":
+		amount = quote.DivRound(p, precision)
+		sizing = fmt.Sprintf("%s %s at %s", quoteAmount, info.QuoteAsset, price)
+	case strings.HasSuffix(quantity, "%equity"):
+		equity, err := account.equity(info.QuoteAsset)
+		if err != nil {
+			return "", "", errors.Trace(err)
+		}
+		pct := strings.TrimSuffix(quantity, "equity")
+		amount = equity.Mul(decimal.NewFromFloat(StrToPct(pct))).DivRound(p, precision)
+		sizing = fmt.Sprintf("%s of equity %s %s at %s", pct, equity, info.QuoteAsset, price)
+	case side == "SELL" || side == "BUY":
+		asset := info.BaseAsset
+		if side == "BUY" {
+			asset = info.QuoteAsset
+		}
+		balance, err := account.freeBalance(asset, accountBalances)
+		if err != nil {
+			return "", "", errors.Trace(err)
+		}
+		if quantity == "max" {
+			rate, err := account.CommissionRate()
+			if err != nil {
+				return "", "", errors.Trace(err)
+			}
+			amount = balance.Mul(decimal.NewFromInt(1).Sub(rate))
+			sizing = fmt.Sprintf("free %s %s less commission %s%%", balance, asset, rate.Mul(decimal.NewFromInt(100)))
+		} else {
+			amount = balance.Mul(decimal.NewFromFloat(StrToPct(quantity)))
+			sizing = fmt.Sprintf("%s of free %s %s", quantity, balance, asset)
+		}
+		if side == "BUY" {
+			amount = amount.DivRound(p, precision)
+			sizing = fmt.Sprintf("%s at %s", sizing, price)
+		}
+	default:
+		return "", "", errors.Errorf("invalid side %s", side)
+	}
+	newQuantity, err := lotQuantity(info, amount)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	return newQuantity, sizing, nil
+}
+
+// lotQuantity round amount of base asset down to lot size of symbol
+func lotQuantity(info binance.Symbol, amount decimal.Decimal) (string, error) {
+	lotSize := info.LotSizeFilter()
+	if lotSize == nil {
+		return "", errors.Errorf("lot size filter of %s not found", info.Symbol)
+	}
+	return AmountToLotSize(amount.String(), lotSize.MinQuantity, lotSize.StepSize, info.BaseAssetPrecision), nil
+}
+
+// equity return total value of balances in quote asset
+func (account *Account) equity(quote string) (decimal.Decimal, error) {
+	balances, err := account.ListBalances()
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	amounts := make(map[string]decimal.Decimal)
+	for asset, balance := range balances {
+		amount := decimal.RequireFromString(balance.Free).Add(decimal.RequireFromString(balance.Locked))
+		if amount.IsPositive() {
+			amounts[asset] = amount
+		}
+	}
+	prices, err := account.ListPrices("")
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	priceMap := make(map[string]string)
+	for _, p := range prices {
+		priceMap[p.Symbol] = p.Price
+	}
+	return EquityValue(amounts, priceMap, quote), nil
+}
+
 func createOrder(c *cli.Context) error {
+	if c.String("from-file") != "" {
+		return createBatchOrders(c)
+	}
 	config, err := loadConfig(c)
 	if err != nil {
 		return errors.Trace(err)
@@ -152,86 +387,120 @@ func createOrder(c *cli.Context) error {
 	symbol := c.String("symbol")
 	side := c.String("side")
 	quantity := c.String("quantity")
+	quoteAmount := c.String("quote-amount")
 	price := c.String("price")
+	tag := c.String("tag")
 	isTest := c.Bool("test")
-	return accountsDo(
-		func(account *Account) (interface{}, error) {
-			newQuantity := quantity
-			if strings.HasSuffix(quantity, "%") {
-				err := account.loadSymbols()
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				info, ok := symbols[symbol]
-				if !ok {
-					return nil, errors.Errorf("symbol %s not found", symbol)
-				}
-				lotSize := info.LotSizeFilter()
-				if lotSize == nil {
-					return nil, errors.Trace(err)
-				}
-				minQty := decimal.RequireFromString(lotSize.MinQuantity)
-				stepSize := decimal.RequireFromString(lotSize.StepSize)
-				precision := info.BaseAssetPrecision
-
-				balances, err := account.ListBalances()
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				var amount decimal.Decimal
-				if side == "SELL" {
-					balance, ok := balances[info.BaseAsset]
-					if !ok {
-						return nil, errors.Errorf("balance %s not found", symbol)
-					}
-					amount = decimal.RequireFromString(balance.Free)
-
-					if balanceMap, ok := accountBalances[account.Name]; ok {
-						if b, ok := balanceMap[info.BaseAsset]; ok {
-							if b.Locked != "" {
-								amount = amount.Sub(decimal.RequireFromString(b.Locked))
-							}
-						}
-					}
-
-					percent := decimal.NewFromFloat(StrToPct(quantity))
-					amount = amount.Mul(percent)
-				} else if side == "BUY" {
-					balance, ok := balances[info.QuoteAsset]
-					if !ok {
-						return nil, errors.Errorf("balance %s not found", symbol)
-					}
-					amount = decimal.RequireFromString(balance.Free)
+	if err := CheckTag(tag); err != nil {
+		return errors.Trace(err)
+	}
+	if quoteAmount != "" {
+		if q, err := decimal.NewFromString(quoteAmount); err != nil || !q.IsPositive() {
+			return errors.Errorf("invalid quote amount %q", quoteAmount)
+		}
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	var splits map[string]string
+	if c.Bool("split-even") {
+		splits, err = splitEven(symbol, side, quantity)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	planAction := func(account *Account) (interface{}, error) {
+		resolvedPrice, err := account.resolvePrice(symbol, price)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		orderQuantity, orderQuoteAmount := quantity, quoteAmount
+		if splits != nil {
+			split, ok := splits[account.Name]
+			if !ok || !decimal.RequireFromString(split).IsPositive() {
+				return nil, errors.Errorf("nothing to %s, holding is already beyond even exposure", side)
+			}
+			orderQuantity, orderQuoteAmount = split, ""
+		}
+		plan, err := account.planOrder(symbol, side, orderQuantity, orderQuoteAmount, resolvedPrice, tag, accountBalances)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if splits != nil {
+			plan.Sizing = fmt.Sprintf("even split of %s", quantity)
+		}
+		if resolvedPrice != price {
+			plan.PriceExpr = price
+		}
+		return plan, nil
+	}
+	if c.Bool("plan") {
+		return accountsDo(planAction)
+	}
+	return createPlannedOrders(config, accountsRun(planAction), isTest)
+}
 
-					if balanceMap, ok := accountBalances[account.Name]; ok {
-						if b, ok := balanceMap[info.QuoteAsset]; ok {
-							if b.Locked != "" {
-								amount = amount.Sub(decimal.RequireFromString(b.Locked))
-							}
-						}
-					}
+// assetHolding return free plus locked balance of asset, asset without
+// balance row is held zero
+func assetHolding(balances map[string]binance.Balance, asset string) (decimal.Decimal, error) {
+	balance, ok := balances[asset]
+	if !ok {
+		return decimal.Decimal{}, nil
+	}
+	free, err := decimal.NewFromString(balance.Free)
+	if err != nil {
+		return decimal.Decimal{}, errors.Errorf("invalid free balance %s of %s", balance.Free, asset)
+	}
+	locked, err := decimal.NewFromString(balance.Locked)
+	if err != nil {
+		return decimal.Decimal{}, errors.Errorf("invalid locked balance %s of %s", balance.Locked, asset)
+	}
+	return free.Add(locked), nil
+}
 
-					percent := decimal.NewFromFloat(StrToPct(quantity))
-					amount = amount.Mul(percent)
-					p := decimal.RequireFromString(price)
-					amount = amount.DivRound(p, int32(precision))
-				}
-				newQuantity = AmountToLotSize(amount.String(), minQty.String(), stepSize.String(), precision)
+// splitEven split total quantity between accounts so every account ends with
+// equal holding of base asset, quantities are rounded to lot size
+func splitEven(symbol, side, quantity string) (map[string]string, error) {
+	total, err := decimal.NewFromString(quantity)
+	if err != nil {
+		return nil, errors.Errorf("split even requires absolute quantity, got %s", quantity)
+	}
+	holdings := accountsRun(
+		func(account *Account) (interface{}, error) {
+			err := account.loadSymbols()
+			if err != nil {
+				return nil, errors.Trace(err)
 			}
-
-			if isTest {
-				err := account.TestCreateOrder(symbol, side, newQuantity, price)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				return "ok", nil
+			info, ok := symbols[symbol]
+			if !ok {
+				return nil, errors.Errorf("symbol %s not found", symbol)
 			}
-			res, err := account.CreateOrder(symbol, side, newQuantity, price)
+			balances, err := account.ListBalances()
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
-			return res.OrderID, nil
+			return assetHolding(balances, info.BaseAsset)
 		})
+	amounts := make(map[string]decimal.Decimal)
+	for name, res := range holdings {
+		amount, ok := res.(decimal.Decimal)
+		if !ok {
+			return nil, errors.Errorf("failed to get holding of %s: %s", name, resultError(res))
+		}
+		amounts[name] = amount
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", symbol)
+	}
+	splits := make(map[string]string)
+	for name, amount := range SplitEven(amounts, total, side) {
+		splits[name], err = lotQuantity(info, amount)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return splits, nil
 }
 
 func listSymbols(c *cli.Context) error {
@@ -265,3 +534,134 @@ func listTrades(c *cli.Context) error {
 			return trades, nil
 		})
 }
+
+// checkOrderID check order id, which is only unique within symbol of an
+// account, is given with symbol and account name
+func checkOrderID(symbol string, orderID int64, accountName string) error {
+	if orderID == 0 {
+		return nil
+	}
+	if symbol == "" {
+		return errors.New("symbol is required with order id")
+	}
+	if accountName == "" {
+		return errors.New("order id is only unique within an account, --name is required with it")
+	}
+	return nil
+}
+
+// checkOrderLookup check symbol and order id or client order id of order
+// queried on accounts
+func checkOrderLookup(symbol string, orderID int64, clientOrderID, accountName string) error {
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if orderID == 0 && clientOrderID == "" {
+		return errors.New("order id or client id is required")
+	}
+	return errors.Trace(checkOrderID(symbol, orderID, accountName))
+}
+
+func getOrder(c *cli.Context) error {
+	symbol := c.String("symbol")
+	orderID := c.Int64("id")
+	clientOrderID := c.String("client-id")
+	if err := checkOrderLookup(symbol, orderID, clientOrderID, name); err != nil {
+		return errors.Trace(err)
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			order, err := account.GetOrder(symbol, orderID, clientOrderID)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return order, nil
+		})
+}
+
+// isOrderFinished check if order reaches a terminal state
+func isOrderFinished(order *binance.Order) bool {
+	switch order.Status {
+	case binance.OrderStatusTypeFilled, binance.OrderStatusTypeCanceled,
+		binance.OrderStatusTypeExpired, binance.OrderStatusTypeRejected:
+		return true
+	}
+	return false
+}
+
+// waitOrderError return error if order of any account is not found, not
+// finished before timeout, rejected or expired
+func waitOrderError(results map[string]interface{}) error {
+	var failed []string
+	for name, res := range results {
+		order, ok := res.(*binance.Order)
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, resultError(res)))
+			continue
+		}
+		if order.Status == binance.OrderStatusTypeRejected || order.Status == binance.OrderStatusTypeExpired {
+			failed = append(failed, fmt.Sprintf("%s: order %d is %s", name, order.OrderID, order.Status))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.Errorf("wait order failed on %d accounts: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// pollOrder query order until it is finished or deadline
+func (account *Account) pollOrder(symbol string, orderID int64, clientOrderID string,
+	interval time.Duration, deadline time.Time) (*binance.Order, error) {
+	for {
+		order, err := account.GetOrder(symbol, orderID, clientOrderID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if isOrderFinished(order) {
+			return order, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("timeout waiting for order %d, status %s", order.OrderID, order.Status)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func waitOrder(c *cli.Context) error {
+	symbol := c.String("symbol")
+	orderID := c.Int64("id")
+	clientOrderID := c.String("client-id")
+	interval := c.Duration("interval")
+	timeout := c.Duration("timeout")
+	if err := checkOrderLookup(symbol, orderID, clientOrderID, name); err != nil {
+		return errors.Trace(err)
+	}
+
+	// accounts are polled concurrently against one deadline
+	deadline := time.Now().Add(timeout)
+	results := make(map[string]interface{})
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, account := range findAccounts(name) {
+		wg.Add(1)
+		go func(account *Account) {
+			defer wg.Done()
+			var res interface{}
+			order, err := account.pollOrder(symbol, orderID, clientOrderID, interval, deadline)
+			if err != nil {
+				res = &accountError{err: err}
+			} else {
+				res = order
+			}
+			mu.Lock()
+			results[account.Name] = res
+			mu.Unlock()
+		}(account)
+	}
+	wg.Wait()
+	if err := print(results); err != nil {
+		return errors.Trace(err)
+	}
+	return waitOrderError(results)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitOrderError(t *testing.T) {
+	tests := []struct {
+		results map[string]interface{}
+		failed  bool
+	}{
+		{map[string]interface{}{"a": &binance.Order{Status: binance.OrderStatusTypeFilled}}, false},
+		{map[string]interface{}{"a": &binance.Order{Status: binance.OrderStatusTypeCanceled}}, false},
+		{map[string]interface{}{"a": &binance.Order{Status: binance.OrderStatusTypeRejected}}, true},
+		{map[string]interface{}{"a": &binance.Order{Status: binance.OrderStatusTypeExpired}}, true},
+		{map[string]interface{}{
+			"a": &binance.Order{Status: binance.OrderStatusTypeFilled},
+			"b": &accountError{err: errors.New("timeout waiting for order 1, status NEW")},
+		}, true},
+		{map[string]interface{}{"a": &accountError{err: errors.New("order does not exist")}}, true},
+	}
+	for _, test := range tests {
+		err := waitOrderError(test.results)
+		assert.Equal(t, test.failed, err != nil, "%v", test.results)
+	}
+}
+
+func TestCheckOrderLookup(t *testing.T) {
+	tests := []struct {
+		symbol        string
+		orderID       int64
+		clientOrderID string
+		accountName   string
+		valid         bool
+	}{
+		{"BNBUSDT", 123, "", "account1", true},
+		{"BNBUSDT", 0, "mm1-xxx", "", true},
+		{"BNBUSDT", 123, "", "", false},
+		{"BNBUSDT", 123, "mm1-xxx", "", false},
+		{"", 0, "mm1-xxx", "", false},
+		{"BNBUSDT", 0, "", "account1", false},
+	}
+	for _, test := range tests {
+		err := checkOrderLookup(test.symbol, test.orderID, test.clientOrderID, test.accountName)
+		assert.Equal(t, test.valid, err == nil, "%v", test)
+	}
+}
+
+func TestSizeOrderInvalidInput(t *testing.T) {
+	account := &Account{Name: "a"}
+	tests := []struct {
+		quantity, quoteAmount, price string
+	}{
+		{"", "abc", "20"},
+		{"", "-100", "20"},
+		{"", "0", "20"},
+		{"50%", "", "abc"},
+		{"", "100", "0"},
+	}
+	for _, test := range tests {
+		_, _, err := account.sizeOrder("BNBUSDT", "BUY", test.quantity, test.quoteAmount, test.price, nil)
+		assert.Error(t, err, "%+v", test)
+	}
+}
+
+func TestAssetHolding(t *testing.T) {
+	assert := assert.New(t)
+	balances := map[string]binance.Balance{
+		"BNB": {Asset: "BNB", Free: "1.5", Locked: "0.5"},
+		"BAD": {Asset: "BAD", Free: "", Locked: "0"},
+	}
+	holding, err := assetHolding(balances, "BNB")
+	assert.NoError(err)
+	assert.Equal("2", holding.String())
+
+	holding, err = assetHolding(balances, "BTC")
+	assert.NoError(err)
+	assert.True(holding.IsZero())
+
+	_, err = assetHolding(balances, "BAD")
+	assert.Error(err)
+}
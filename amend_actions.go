@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// AmendResult define replacement of an open order
+type AmendResult struct {
+	Symbol           string `json:"symbol"`
+	Side             string `json:"side"`
+	OrderID          int64  `json:"order_id"`
+	ClientOrderID    string `json:"client_order_id"`
+	Price            string `json:"price"`
+	Quantity         string `json:"quantity"`
+	NewOrderID       int64  `json:"new_order_id,omitempty"`
+	NewClientOrderID string `json:"new_client_order_id"`
+	NewPrice         string `json:"new_price"`
+	NewQuantity      string `json:"new_quantity"`
+	// Filled is quantity filled after the replacement is computed and before
+	// the original order is canceled
+	Filled     string `json:"filled,omitempty"`
+	RolledBack bool   `json:"rolled_back,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// amendOrder compute replacement of order with shifted price and quantity,
+// rounded to tick size and lot size of symbol
+func (account *Account) amendOrder(order *binance.Order, priceExpr, quantityExpr string) (*AmendResult, error) {
+	err := account.loadSymbols()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := symbols[order.Symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", order.Symbol)
+	}
+	priceFilter := info.PriceFilter()
+	if priceFilter == nil {
+		return nil, errors.Errorf("price filter of %s not found", order.Symbol)
+	}
+	lotSize := info.LotSizeFilter()
+	if lotSize == nil {
+		return nil, errors.Errorf("lot size filter of %s not found", order.Symbol)
+	}
+	remaining := decimal.RequireFromString(order.OrigQuantity).
+		Sub(decimal.RequireFromString(order.ExecutedQuantity)).String()
+	result := &AmendResult{
+		Symbol:           order.Symbol,
+		Side:             string(order.Side),
+		OrderID:          order.OrderID,
+		ClientOrderID:    order.ClientOrderID,
+		Price:            order.Price,
+		Quantity:         remaining,
+		NewClientOrderID: AmendClientOrderID(order.ClientOrderID),
+		NewPrice:         order.Price,
+		NewQuantity:      remaining,
+	}
+	if priceExpr != "" {
+		price, err := ShiftValue(order.Price, priceExpr, priceFilter.TickSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result.NewPrice = RoundToTickSize(price, priceFilter.TickSize)
+	}
+	if quantityExpr != "" {
+		quantity, err := ShiftValue(remaining, quantityExpr, lotSize.StepSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result.NewQuantity = AmountToLotSize(quantity, lotSize.MinQuantity, lotSize.StepSize, info.BaseAssetPrecision)
+	}
+	if !decimal.RequireFromString(result.NewPrice).IsPositive() {
+		return nil, errors.Errorf("invalid new price %s of order %d", result.NewPrice, order.OrderID)
+	}
+	if !decimal.RequireFromString(result.NewQuantity).IsPositive() {
+		return nil, errors.Errorf("invalid new quantity %s of order %d", result.NewQuantity, order.OrderID)
+	}
+	return result, nil
+}
+
+// applyCanceled update remaining quantity of result from executed quantity of
+// canceled order, quantity filled since the replacement is computed is taken
+// off new quantity
+func applyCanceled(result *AmendResult, canceled *binance.CancelOrderResponse) error {
+	remaining := decimal.RequireFromString(canceled.OrigQuantity).
+		Sub(decimal.RequireFromString(canceled.ExecutedQuantity))
+	filled := decimal.RequireFromString(result.Quantity).Sub(remaining)
+	if !filled.IsPositive() {
+		return nil
+	}
+	result.Filled = filled.String()
+	result.Quantity = remaining.String()
+	newQuantity := decimal.RequireFromString(result.NewQuantity).Sub(filled)
+	if !newQuantity.IsPositive() {
+		result.NewQuantity = "0"
+		return errors.Errorf("order %d filled %s before cancel, nothing to replace", result.OrderID, filled)
+	}
+	result.NewQuantity = newQuantity.String()
+	return nil
+}
+
+// replaceOrder cancel the original order and create the replacement of its
+// unfilled quantity, the unfilled remainder of original order is restored if
+// the replacement is rejected
+func (account *Account) replaceOrder(result *AmendResult) {
+	canceled, err := account.CancelOrder(result.Symbol, result.OrderID)
+	if err != nil {
+		result.Error = fmt.Sprintf("cancel: %s", err)
+		return
+	}
+	if err := applyCanceled(result, canceled); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	res, err := account.CreateOrder(result.Symbol, result.Side, result.NewQuantity, result.NewPrice, result.NewClientOrderID)
+	if err == nil {
+		result.NewOrderID = res.OrderID
+		return
+	}
+	result.Error = fmt.Sprintf("replace: %s", err)
+	res, err = account.CreateOrder(result.Symbol, result.Side, result.Quantity, result.Price, result.ClientOrderID)
+	if err != nil {
+		result.Error = fmt.Sprintf("%s, rollback: %s", result.Error, err)
+		return
+	}
+	result.NewOrderID = res.OrderID
+	result.RolledBack = true
+}
+
+func amendOrders(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	orderID := c.Int64("id")
+	priceExpr := c.String("price")
+	quantityExpr := c.String("quantity")
+	isDryRun := c.Bool("dry-run")
+	filter := &OrderFilter{
+		Side:           c.String("side"),
+		Type:           string(binance.OrderTypeLimit),
+		PriceAbove:     c.String("price-above"),
+		PriceBelow:     c.String("price-below"),
+		OlderThan:      c.Duration("older-than"),
+		ClientIDPrefix: c.String("client-id-prefix"),
+	}
+	if err := filter.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if err := checkOrderID(symbol, orderID, name); err != nil {
+		return errors.Trace(err)
+	}
+	if priceExpr == "" && quantityExpr == "" {
+		return errors.New("price or quantity is required")
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			orders, err := account.ListOpenOrders(symbol)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			var amendResults []*AmendResult
+			for _, order := range filter.Filter(orders, time.Now()) {
+				if orderID != 0 && order.OrderID != orderID {
+					continue
+				}
+				result, err := account.amendOrder(order, priceExpr, quantityExpr)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				notional := decimal.RequireFromString(result.NewPrice).Mul(decimal.RequireFromString(result.NewQuantity))
+				if err := config.CheckNotional(account.Name, notional); err != nil {
+					return nil, errors.Trace(err)
+				}
+				amendResults = append(amendResults, result)
+			}
+			return amendResults, nil
+		})
+	if isDryRun {
+		return print(results)
+	}
+	var numOrders, numAccounts int
+	for _, res := range results {
+		if amendResults, ok := res.([]*AmendResult); ok && len(amendResults) > 0 {
+			numOrders += len(amendResults)
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numOrders > 0 {
+		if err := confirm("amend %d orders on %d accounts?", numOrders, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			amendResults, ok := results[account.Name].([]*AmendResult)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			for _, result := range amendResults {
+				account.replaceOrder(result)
+			}
+			return amendResults, nil
+		})
+}
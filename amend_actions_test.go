@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCanceled(t *testing.T) {
+	tests := []struct {
+		quantity, newQuantity string
+		orig, executed        string
+		wantQuantity          string
+		wantNewQuantity       string
+		wantFilled            string
+		wantErr               bool
+	}{
+		// no fill since replacement is computed
+		{"0.6", "0.3", "1", "0.4", "0.6", "0.3", "", false},
+		// partial fill is taken off new quantity and rollback quantity
+		{"0.6", "0.6", "1", "0.5", "0.5", "0.5", "0.1", false},
+		{"0.6", "0.3", "1", "0.5", "0.5", "0.2", "0.1", false},
+		// filled more than new quantity
+		{"0.6", "0.3", "1", "0.8", "0.2", "0", "0.4", true},
+		{"0.6", "0.6", "1", "1", "0", "0", "0.6", true},
+	}
+	for _, test := range tests {
+		result := &AmendResult{OrderID: 1, Quantity: test.quantity, NewQuantity: test.newQuantity}
+		err := applyCanceled(result, &binance.CancelOrderResponse{OrigQuantity: test.orig, ExecutedQuantity: test.executed})
+		assert.Equal(t, test.wantErr, err != nil, "%v", test)
+		assert.Equal(t, test.wantQuantity, result.Quantity, "%v", test)
+		assert.Equal(t, test.wantNewQuantity, result.NewQuantity, "%v", test)
+		assert.Equal(t, test.wantFilled, result.Filled, "%v", test)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchOrder define an order row of batch file and its result
+type BatchOrder struct {
+	// Row is 1-based position of the order in batch file
+	Row           int    `json:"row" yaml:"-"`
+	Account       string `json:"account" yaml:"account"`
+	Symbol        string `json:"symbol" yaml:"symbol"`
+	Side          string `json:"side" yaml:"side"`
+	Type          string `json:"type" yaml:"type"`
+	Quantity      string `json:"quantity" yaml:"quantity"`
+	Price         string `json:"price,omitempty" yaml:"price"`
+	TimeInForce   string `json:"tif,omitempty" yaml:"tif"`
+	ClientOrderID string `json:"client_id,omitempty" yaml:"client_id"`
+	// OrderQuantity and OrderPrice are resolved from percent quantity and
+	// relative price when validated
+	OrderQuantity string `json:"order_quantity,omitempty" yaml:"-"`
+	OrderPrice    string `json:"order_price,omitempty" yaml:"-"`
+	Notional      string `json:"notional,omitempty" yaml:"-"`
+	OrderID       int64  `json:"order_id,omitempty" yaml:"-"`
+	Error         string `json:"error,omitempty" yaml:"-"`
+}
+
+// parseBatchCSV parse orders from csv with header row, header names are case
+// insensitive and spaces are treated as underscores
+func parseBatchCSV(r io.Reader) ([]*BatchOrder, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("header row not found")
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[strings.Replace(name, " ", "_", -1)] = i
+	}
+	for _, name := range []string{"account", "symbol", "side", "quantity"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.Errorf("column %s not found", name)
+		}
+	}
+	get := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	var orders []*BatchOrder
+	for i, record := range records[1:] {
+		orders = append(orders, &BatchOrder{
+			Row:           i + 1,
+			Account:       get(record, "account"),
+			Symbol:        get(record, "symbol"),
+			Side:          get(record, "side"),
+			Type:          get(record, "type"),
+			Quantity:      get(record, "quantity"),
+			Price:         get(record, "price"),
+			TimeInForce:   get(record, "tif"),
+			ClientOrderID: get(record, "client_id"),
+		})
+	}
+	return orders, nil
+}
+
+// loadBatchOrders load orders from csv file, or yaml list if file extension
+// is .yaml or .yml
+func loadBatchOrders(filePath string) ([]*BatchOrder, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext != ".yaml" && ext != ".yml" {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		defer f.Close()
+		return parseBatchCSV(f)
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var orders []*BatchOrder
+	err = yaml.Unmarshal(data, &orders)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, order := range orders {
+		order.Row = i + 1
+	}
+	return orders, nil
+}
+
+// CheckSymbolFilters check quantity and price against lot size, price and min
+// notional filters of symbol, price filter is skipped for MARKET order
+func CheckSymbolFilters(info binance.Symbol, quantity, price string, isMarket bool) error {
+	q, err := decimal.NewFromString(quantity)
+	if err != nil {
+		return errors.Errorf("invalid quantity %s", quantity)
+	}
+	p, err := decimal.NewFromString(price)
+	if err != nil {
+		return errors.Errorf("invalid price %s", price)
+	}
+	if lotSize := info.LotSizeFilter(); lotSize != nil {
+		minQty := decimal.RequireFromString(lotSize.MinQuantity)
+		maxQty := decimal.RequireFromString(lotSize.MaxQuantity)
+		stepSize := decimal.RequireFromString(lotSize.StepSize)
+		if q.LessThan(minQty) {
+			return errors.Errorf("quantity %s is less than min quantity %s", quantity, lotSize.MinQuantity)
+		}
+		if maxQty.IsPositive() && q.GreaterThan(maxQty) {
+			return errors.Errorf("quantity %s is greater than max quantity %s", quantity, lotSize.MaxQuantity)
+		}
+		if stepSize.IsPositive() && !q.Sub(minQty).Mod(stepSize).IsZero() {
+			return errors.Errorf("quantity %s is not multiple of step size %s", quantity, lotSize.StepSize)
+		}
+	}
+	if priceFilter := info.PriceFilter(); priceFilter != nil && !isMarket {
+		minPrice := decimal.RequireFromString(priceFilter.MinPrice)
+		maxPrice := decimal.RequireFromString(priceFilter.MaxPrice)
+		tickSize := decimal.RequireFromString(priceFilter.TickSize)
+		if p.LessThan(minPrice) {
+			return errors.Errorf("price %s is less than min price %s", price, priceFilter.MinPrice)
+		}
+		if maxPrice.IsPositive() && p.GreaterThan(maxPrice) {
+			return errors.Errorf("price %s is greater than max price %s", price, priceFilter.MaxPrice)
+		}
+		if tickSize.IsPositive() && !p.Sub(minPrice).Mod(tickSize).IsZero() {
+			return errors.Errorf("price %s is not multiple of tick size %s", price, priceFilter.TickSize)
+		}
+	}
+	if f := info.MinNotionalFilter(); f != nil && (!isMarket || f.ApplyToMarket) {
+		notional := q.Mul(p)
+		if notional.LessThan(decimal.RequireFromString(f.MinNotional)) {
+			return errors.Errorf("notional %s is less than min notional %s", notional, f.MinNotional)
+		}
+	}
+	return nil
+}
+
+// validateBatchOrder normalize order, resolve its quantity and price, and
+// check it against symbol filters and limits of config
+func (account *Account) validateBatchOrder(config *Config, order *BatchOrder,
+	accountBalances map[string]map[string]binance.Balance) error {
+	order.Side = strings.ToUpper(order.Side)
+	order.Type = strings.ToUpper(order.Type)
+	order.TimeInForce = strings.ToUpper(order.TimeInForce)
+	if order.Type == "" {
+		order.Type = string(binance.OrderTypeLimit)
+	}
+	if order.Side != "BUY" && order.Side != "SELL" {
+		return errors.Errorf("invalid side %s", order.Side)
+	}
+	if err := CheckClientOrderID(order.ClientOrderID); err != nil {
+		return errors.Trace(err)
+	}
+	if err := config.CheckSymbol(order.Symbol); err != nil {
+		return errors.Trace(err)
+	}
+	err := account.loadSymbols()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info, ok := symbols[order.Symbol]
+	if !ok {
+		return errors.Errorf("symbol %s not found", order.Symbol)
+	}
+
+	isMarket := false
+	switch binance.OrderType(order.Type) {
+	case binance.OrderTypeLimit:
+		if order.Price == "" {
+			return errors.New("price is required by LIMIT order")
+		}
+		if order.TimeInForce == "" {
+			order.TimeInForce = string(binance.TimeInForceTypeGTC)
+		}
+		switch binance.TimeInForceType(order.TimeInForce) {
+		case binance.TimeInForceTypeGTC, binance.TimeInForceTypeIOC, binance.TimeInForceTypeFOK:
+		default:
+			return errors.Errorf("invalid tif %s", order.TimeInForce)
+		}
+		order.OrderPrice, err = account.resolvePrice(order.Symbol, order.Price)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	case binance.OrderTypeMarket:
+		if order.Price != "" || order.TimeInForce != "" {
+			return errors.New("price and tif are not allowed by MARKET order")
+		}
+		isMarket = true
+		order.OrderPrice, err = account.marketPrice(order.Symbol)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	default:
+		return errors.Errorf("invalid type %s", order.Type)
+	}
+
+	order.OrderQuantity, err = account.orderQuantity(order.Symbol, order.Side, order.Quantity, order.OrderPrice, accountBalances)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := CheckSymbolFilters(info, order.OrderQuantity, order.OrderPrice, isMarket); err != nil {
+		return errors.Trace(err)
+	}
+	notional := decimal.RequireFromString(order.OrderQuantity).Mul(decimal.RequireFromString(order.OrderPrice))
+	if err := config.CheckNotional(account.Name, notional); err != nil {
+		return errors.Trace(err)
+	}
+	order.Notional = notional.String()
+	if isMarket {
+		// price of MARKET order is only used to estimate notional
+		order.OrderPrice = ""
+	}
+	return nil
+}
+
+// submitBatchOrders create orders with at most concurrency requests in flight
+func submitBatchOrders(accounts map[string]*Account, orders []*BatchOrder, concurrency int, isTest bool) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(order *BatchOrder) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := accounts[order.Account].CreateTypedOrder(order.Symbol, order.Side, order.Type,
+				order.TimeInForce, order.OrderQuantity, order.OrderPrice, order.ClientOrderID, isTest)
+			if err != nil {
+				order.Error = err.Error()
+				return
+			}
+			if res != nil {
+				order.OrderID = res.OrderID
+			}
+		}(order)
+	}
+	wg.Wait()
+}
+
+func createBatchOrders(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+	filePath := c.String("from-file")
+	resultsPath := c.String("results")
+	isTest := c.Bool("test")
+	if resultsPath == "" {
+		resultsPath = strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".results.json"
+	}
+	orders, err := loadBatchOrders(filePath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(orders) == 0 {
+		return errors.Errorf("no order found in %s", filePath)
+	}
+
+	// validate every row before sending anything
+	accounts := findAccounts(name)
+	orderAccounts := make(map[string]bool)
+	total := decimal.Decimal{}
+	numInvalid := 0
+	for _, order := range orders {
+		account, ok := accounts[order.Account]
+		if !ok || account == nil {
+			order.Error = fmt.Sprintf("account %s not found", order.Account)
+			numInvalid++
+			continue
+		}
+		if err := account.validateBatchOrder(config, order, accountBalances); err != nil {
+			order.Error = err.Error()
+			numInvalid++
+			continue
+		}
+		orderAccounts[order.Account] = true
+		total = total.Add(decimal.RequireFromString(order.Notional))
+	}
+	if numInvalid > 0 {
+		if err := writeJSONFile(resultsPath, orders); err != nil {
+			return errors.Trace(err)
+		}
+		if err := print(orders); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Errorf("%d of %d orders are invalid, nothing is sent, see %s", numInvalid, len(orders), resultsPath)
+	}
+	if err := config.CheckAccounts(len(orderAccounts)); err != nil {
+		return errors.Trace(err)
+	}
+	if !isTest {
+		if err := confirm("create %d orders on %d accounts with total notional %s?", len(orders), len(orderAccounts), total); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	submitBatchOrders(accounts, orders, c.Int("concurrency"), isTest)
+	if err := writeJSONFile(resultsPath, orders); err != nil {
+		return errors.Trace(err)
+	}
+	return print(orders)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseBatchCSV(t *testing.T) {
+	assert := assert.New(t)
+	data := "Account,Symbol,Side,Type,Quantity,Price,TIF,Client ID\n" +
+		"a1,BNBUSDT,BUY,LIMIT,10,20,GTC,mm1\n" +
+		"a2,BNBUSDT,sell,MARKET,50%,,,\n"
+	orders, err := parseBatchCSV(strings.NewReader(data))
+	assert.NoError(err)
+	assert.Equal([]*BatchOrder{
+		{Row: 1, Account: "a1", Symbol: "BNBUSDT", Side: "BUY", Type: "LIMIT", Quantity: "10", Price: "20", TimeInForce: "GTC", ClientOrderID: "mm1"},
+		{Row: 2, Account: "a2", Symbol: "BNBUSDT", Side: "sell", Type: "MARKET", Quantity: "50%"},
+	}, orders)
+
+	_, err = parseBatchCSV(strings.NewReader("account,symbol,side\na1,BNBUSDT,BUY\n"))
+	assert.Error(err)
+}
+
+func TestBatchOrderYAML(t *testing.T) {
+	assert := assert.New(t)
+	data := "- account: a1\n  symbol: BNBUSDT\n  side: BUY\n  quantity: 10\n  price: 20.5\n"
+	var orders []*BatchOrder
+	assert.NoError(yaml.Unmarshal([]byte(data), &orders))
+	assert.Equal([]*BatchOrder{
+		{Account: "a1", Symbol: "BNBUSDT", Side: "BUY", Quantity: "10", Price: "20.5"},
+	}, orders)
+}
+
+func TestCheckSymbolFilters(t *testing.T) {
+	assert := assert.New(t)
+	info := binance.Symbol{
+		Symbol: "BNBUSDT",
+		Filters: []map[string]interface{}{
+			{"filterType": "LOT_SIZE", "minQty": "0.01", "maxQty": "1000", "stepSize": "0.01"},
+			{"filterType": "PRICE_FILTER", "minPrice": "0.0001", "maxPrice": "10000", "tickSize": "0.0001"},
+			{"filterType": "MIN_NOTIONAL", "minNotional": "10", "applyToMarket": true, "avgPriceMins": float64(5)},
+		},
+	}
+	tests := []struct {
+		name     string
+		quantity string
+		price    string
+		isMarket bool
+		err      bool
+	}{
+		{
+			name:     "test with valid order",
+			quantity: "1.5",
+			price:    "20.1234",
+		},
+		{
+			name:     "test with quantity below min",
+			quantity: "0.001",
+			price:    "20",
+			err:      true,
+		},
+		{
+			name:     "test with quantity off step",
+			quantity: "1.505",
+			price:    "20",
+			err:      true,
+		},
+		{
+			name:     "test with price off tick",
+			quantity: "1",
+			price:    "20.00001",
+			err:      true,
+		},
+		{
+			name:     "test with market skipping price filter",
+			quantity: "1",
+			price:    "20.00001",
+			isMarket: true,
+		},
+		{
+			name:     "test with notional below min",
+			quantity: "0.1",
+			price:    "20",
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSymbolFilters(info, tt.quantity, tt.price, tt.isMarket)
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
@@ -13,7 +13,28 @@ var config = new(Config)
 
 // Config define cli config
 type Config struct {
-	Accounts []AccountConfig `json:"accounts"`
+	Accounts    []AccountConfig   `json:"accounts"`
+	Limits      Limits            `json:"limits"`
+	MarginGuard MarginGuardConfig `json:"margin_guard"`
+}
+
+// MarginGuardConfig define thresholds of margin-guard, margin level is total
+// asset divided by total liability of margin account
+type MarginGuardConfig struct {
+	Warn     string `json:"warn"`
+	Critical string `json:"critical"`
+	// Target is the margin level restored when critical is reached
+	Target string `json:"target"`
+	// Collateral is sold to repay loans when free balance is not enough
+	Collateral string `json:"collateral"`
+}
+
+// Limits define guardrails of destructive commands
+type Limits struct {
+	MaxOrderNotional string   `json:"max_order_notional"`
+	AllowSymbols     []string `json:"allow_symbols"`
+	DenySymbols      []string `json:"deny_symbols"`
+	MaxAccounts      int      `json:"max_accounts"`
 }
 
 // AccountBalances return account balance map
@@ -30,8 +51,9 @@ func (c *Config) AccountBalances() map[string]map[string]binance.Balance {
 
 // AccountConfig define account config
 type AccountConfig struct {
-	Name     string            `json:"name"`
-	Balances []binance.Balance `json:"balances"`
+	Name             string            `json:"name"`
+	Balances         []binance.Balance `json:"balances"`
+	MaxOrderNotional string            `json:"max_order_notional,omitempty"`
 }
 
 func loadConfig(c *cli.Context) (*Config, error) {
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// ExecutionSlice define a child order of execution algorithm
+type ExecutionSlice struct {
+	Slice    int    `json:"slice"`
+	Time     int64  `json:"time"`
+	Target   string `json:"target"`
+	Quantity string `json:"quantity"`
+	Price    string `json:"price,omitempty"`
+	Filled   string `json:"filled"`
+	AvgPrice string `json:"avg_price,omitempty"`
+	OrderID  int64  `json:"order_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ExecutionReport define progress of execution algorithm of an account
+type ExecutionReport struct {
+	Account      string            `json:"account"`
+	Symbol       string            `json:"symbol"`
+	Side         string            `json:"side"`
+	Target       string            `json:"target"`
+	Filled       string            `json:"filled"`
+	Remaining    string            `json:"remaining"`
+	AvgPrice     string            `json:"avg_price"`
+	ArrivalPrice string            `json:"arrival_price"`
+	SlippageBps  string            `json:"slippage_bps"`
+	Slices       []*ExecutionSlice `json:"slices"`
+}
+
+// executor send child orders of a parent order of an account
+type executor struct {
+	account     *Account
+	config      *Config
+	info        binance.Symbol
+	orderType   string
+	limitPrice  string
+	tag         string
+	report      *ExecutionReport
+	target      decimal.Decimal
+	filled      decimal.Decimal
+	filledQuote decimal.Decimal
+	// resting is the passive LIMIT child order waiting for next slice
+	resting *ExecutionSlice
+}
+
+func newExecutor(account *Account, config *Config, symbol, side, quantity, orderType, limitPrice, tag string) (*executor, error) {
+	err := account.loadSymbols()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", symbol)
+	}
+	arrival, err := account.MidPrice(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	target, err := account.orderQuantity(symbol, side, quantity, arrival.String(), config.AccountBalances())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	e := &executor{
+		account:    account,
+		config:     config,
+		info:       info,
+		orderType:  orderType,
+		limitPrice: limitPrice,
+		tag:        tag,
+		target:     decimal.RequireFromString(target),
+		report: &ExecutionReport{
+			Account:      account.Name,
+			Symbol:       symbol,
+			Side:         side,
+			Target:       target,
+			ArrivalPrice: arrival.String(),
+		},
+	}
+	e.update()
+	return e, nil
+}
+
+func (e *executor) remaining() decimal.Decimal {
+	return e.target.Sub(e.filled)
+}
+
+// BookPrice return price of LIMIT order of side, a passive order joins the
+// best price of its own side of book, otherwise it crosses the spread
+func BookPrice(side, bid, ask string, passive bool) string {
+	if (side == "BUY") == passive {
+		return bid
+	}
+	return ask
+}
+
+// childPrice return price of LIMIT child order at the best price of book,
+// empty price means the limit price is not reached
+func (e *executor) childPrice(passive bool) (string, error) {
+	tickers, err := e.account.ListBookTickers(e.info.Symbol)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(tickers) == 0 {
+		return "", errors.Errorf("book ticker of %s not found", e.info.Symbol)
+	}
+	price := decimal.RequireFromString(BookPrice(e.report.Side, tickers[0].BidPrice, tickers[0].AskPrice, passive))
+	if e.limitPrice != "" {
+		limit := decimal.RequireFromString(e.limitPrice)
+		if (e.report.Side == "BUY" && price.GreaterThan(limit)) ||
+			(e.report.Side == "SELL" && price.LessThan(limit)) {
+			return "", nil
+		}
+	}
+	return price.String(), nil
+}
+
+// sendChild send a child order of amount rounded to lot size, amount under
+// lot size or min notional is skipped and left to later slices. A passive
+// LIMIT child order rests on book until settleChild, otherwise it crosses the
+// spread and is canceled if not filled immediately
+func (e *executor) sendChild(slice int, amount decimal.Decimal, passive bool) *ExecutionSlice {
+	s := &ExecutionSlice{Slice: slice, Time: time.Now().Unix(), Target: amount.String(), Filled: "0"}
+	e.report.Slices = append(e.report.Slices, s)
+	defer e.update()
+
+	lotSize := e.info.LotSizeFilter()
+	if lotSize == nil {
+		s.Error = fmt.Sprintf("lot size filter of %s not found", e.info.Symbol)
+		return s
+	}
+	amount = decimal.Min(amount, e.remaining())
+	s.Quantity = AmountToLotSize(amount.String(), lotSize.MinQuantity, lotSize.StepSize, e.info.BaseAssetPrecision)
+	quantity := decimal.RequireFromString(s.Quantity)
+	if !quantity.IsPositive() {
+		s.Error = "below lot size"
+		return s
+	}
+	price, err := e.childPrice(passive)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	if price == "" {
+		s.Error = "limit price not reached"
+		return s
+	}
+	if f := e.info.MinNotionalFilter(); f != nil &&
+		quantity.Mul(decimal.RequireFromString(price)).LessThan(decimal.RequireFromString(f.MinNotional)) {
+		s.Error = "below min notional"
+		return s
+	}
+	if err := e.config.CheckNotional(e.account.Name, quantity.Mul(decimal.RequireFromString(price))); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	clientOrderID := ClientOrderID(e.tag, e.account.Name, e.info.Symbol, e.report.Side, strconv.Itoa(slice))
+	var res *binance.CreateOrderResponse
+	isMarket := e.orderType == "market" && e.limitPrice == ""
+	switch {
+	case isMarket:
+		res, err = e.account.CreateMarketOrder(e.info.Symbol, e.report.Side, s.Quantity, clientOrderID)
+	case passive:
+		s.Price = price
+		res, err = e.account.CreateOrder(e.info.Symbol, e.report.Side, s.Quantity, price, clientOrderID)
+	default:
+		s.Price = price
+		res, err = e.account.CreateIOCOrder(e.info.Symbol, e.report.Side, s.Quantity, price, clientOrderID)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.OrderID = res.OrderID
+	if passive && !isMarket {
+		// fills of resting order are recorded when it is settled
+		e.resting = s
+		return s
+	}
+	e.recordFill(s, res.ExecutedQuantity, res.CummulativeQuoteQuantity)
+	return s
+}
+
+// recordFill add executed quantity and quote quantity of child order
+func (e *executor) recordFill(s *ExecutionSlice, executed, quote string) {
+	filled := decimal.RequireFromString(executed)
+	quoteQuantity := decimal.RequireFromString(quote)
+	s.Filled = filled.String()
+	if filled.IsPositive() {
+		s.AvgPrice = quoteQuantity.Div(filled).String()
+	}
+	e.filled = e.filled.Add(filled)
+	e.filledQuote = e.filledQuote.Add(quoteQuantity)
+}
+
+// settleChild cancel the resting child order and record its fills, the
+// order is kept resting if its fills can not be read, so no more child order
+// is sent on top of unknown fills
+func (e *executor) settleChild() error {
+	s := e.resting
+	if s == nil {
+		return nil
+	}
+	defer e.update()
+	_, err := e.account.CancelOrder(e.info.Symbol, s.OrderID)
+	if err != nil {
+		log.Printf("%s: cancel child order %d: %s", e.account.Name, s.OrderID, err)
+	}
+	order, err := e.account.GetOrder(e.info.Symbol, s.OrderID, "")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !isOrderFinished(order) {
+		return errors.Errorf("child order %d is still %s", s.OrderID, order.Status)
+	}
+	e.resting = nil
+	e.recordFill(s, order.ExecutedQuantity, order.CummulativeQuoteQuantity)
+	return nil
+}
+
+// update compute filled, average price and slippage against arrival price,
+// positive slippage means worse than arrival price
+func (e *executor) update() {
+	e.report.Filled = e.filled.String()
+	e.report.Remaining = e.remaining().String()
+	if !e.filled.IsPositive() {
+		return
+	}
+	avg := e.filledQuote.Div(e.filled)
+	arrival := decimal.RequireFromString(e.report.ArrivalPrice)
+	slippage := avg.Sub(arrival)
+	if e.report.Side == "SELL" {
+		slippage = slippage.Neg()
+	}
+	e.report.AvgPrice = avg.String()
+	e.report.SlippageBps = slippage.Div(arrival).Mul(decimal.NewFromInt(10000)).StringFixed(2)
+}
+
+// newExecutors create executors of selected accounts after checking limits
+// and confirmation
+func newExecutors(c *cli.Context, algo string) ([]*executor, error) {
+	config, err := loadConfig(c)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	side := c.String("side")
+	quantity := c.String("quantity")
+	if symbol == "" || side == "" || quantity == "" {
+		return nil, errors.New("symbol, side and quantity are required")
+	}
+	if err := CheckTag(c.String("tag")); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var executors []*executor
+	total := decimal.Decimal{}
+	for _, account := range findAccounts(name) {
+		e, err := newExecutor(account, config, symbol, side, quantity, c.String("type"),
+			c.String("limit-price"), c.String("tag"))
+		if err != nil {
+			log.Printf("%s: %s", account.Name, err)
+			continue
+		}
+		total = total.Add(e.target)
+		executors = append(executors, e)
+	}
+	if err := config.CheckAccounts(len(executors)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := confirm("execute %s %s %s %s on %d accounts?", algo, side, total, symbol, len(executors)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return executors, nil
+}
+
+func executionReports(executors []*executor) map[string]*ExecutionReport {
+	reports := make(map[string]*ExecutionReport)
+	for _, e := range executors {
+		reports[e.account.Name] = e.report
+	}
+	return reports
+}
+
+// sleepUntil wait until t, it returns false if interrupted by signal
+func sleepUntil(t time.Time, stopC chan os.Signal) bool {
+	select {
+	case <-stopC:
+		return false
+	case <-time.After(time.Until(t)):
+		return true
+	}
+}
+
+// TWAPSchedule return send time of each slice, slices after the first are
+// shifted randomly within randomize of half of the interval, rnd returns
+// values in [0, 1)
+func TWAPSchedule(start time.Time, duration time.Duration, slices int, randomize float64, rnd func() float64) []time.Time {
+	interval := duration / time.Duration(slices)
+	schedule := make([]time.Time, slices)
+	for i := range schedule {
+		at := start.Add(interval * time.Duration(i))
+		if i > 0 && randomize > 0 {
+			jitter := (rnd()*2 - 1) * randomize * float64(interval) / 2
+			at = at.Add(time.Duration(jitter))
+		}
+		schedule[i] = at
+	}
+	return schedule
+}
+
+// TWAPSliceAmount return amount of slice i, remaining quantity is spread over
+// the remaining slices so shortfalls of previous slices are caught up
+func TWAPSliceAmount(remaining decimal.Decimal, slices, i int) decimal.Decimal {
+	if i >= slices-1 {
+		return remaining
+	}
+	return remaining.Div(decimal.NewFromInt(int64(slices - i)))
+}
+
+// underfilled return error listing executors with remaining quantity
+func underfilled(executors []*executor) error {
+	var l []string
+	for _, e := range executors {
+		if e.remaining().IsPositive() {
+			l = append(l, fmt.Sprintf("%s: %s", e.account.Name, e.remaining()))
+		}
+	}
+	if len(l) > 0 {
+		return errors.Errorf("execution ended with remaining quantity on %d accounts: %s", len(l), strings.Join(l, ", "))
+	}
+	return nil
+}
+
+func executeTWAP(c *cli.Context) error {
+	duration := c.Duration("duration")
+	slices := c.Int("slices")
+	randomize := StrToPct(c.String("randomize"))
+	reportPath := c.String("report")
+	if slices < 1 {
+		return errors.New("slices must be positive")
+	}
+	executors, err := newExecutors(c, "twap")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(executors) == 0 {
+		return print(executionReports(executors))
+	}
+	reports := executionReports(executors)
+	writeReport := func() {
+		if reportPath != "" {
+			if err := writeJSONFile(reportPath, reports); err != nil {
+				log.Printf("failed to write report: %s", err)
+			}
+		}
+	}
+
+	stopC := make(chan os.Signal, 1)
+	signal.Notify(stopC, os.Interrupt, syscall.SIGTERM)
+	rand.Seed(time.Now().UnixNano())
+	start := time.Now()
+	schedule := TWAPSchedule(start, duration, slices, randomize, rand.Float64)
+	// child orders rest at best bid or ask until next slice, when their fills
+	// are settled before sizing the next one
+	settle := func() {
+		for _, e := range executors {
+			if err := e.settleChild(); err != nil {
+				log.Printf("%s: failed to settle child order: %s", e.account.Name, err)
+			}
+		}
+	}
+	interrupted := false
+	for i, at := range schedule {
+		if !sleepUntil(at, stopC) {
+			interrupted = true
+			break
+		}
+		settle()
+		for _, e := range executors {
+			if e.resting != nil {
+				continue
+			}
+			s := e.sendChild(i, TWAPSliceAmount(e.remaining(), slices, i), true)
+			log.Printf("%s: slice %d placed %s at %s %s", e.account.Name, i, s.Quantity, s.Price, s.Error)
+		}
+		writeReport()
+	}
+	if !interrupted && !sleepUntil(start.Add(duration), stopC) {
+		interrupted = true
+	}
+	settle()
+	if !interrupted {
+		// shortfall of the last slice is caught up once at the end by
+		// crossing the spread
+		for _, e := range executors {
+			if e.resting != nil || !e.remaining().IsPositive() {
+				continue
+			}
+			s := e.sendChild(slices, e.remaining(), false)
+			log.Printf("%s: catch-up filled %s of %s %s", e.account.Name, s.Filled, s.Quantity, s.Error)
+		}
+	}
+	writeReport()
+	for _, e := range executors {
+		if e.resting != nil {
+			log.Printf("%s: child order %d may still be open", e.account.Name, e.resting.OrderID)
+		}
+	}
+	if err := print(reports); err != nil {
+		return errors.Trace(err)
+	}
+	return underfilled(executors)
+}
+
+// ParseChildSizes parse min and max child size, empty size is zero and max
+// child size of zero is unlimited
+func ParseChildSizes(min, max string) (decimal.Decimal, decimal.Decimal, error) {
+	var sizes [2]decimal.Decimal
+	for i, v := range []string{min, max} {
+		if v == "" {
+			continue
+		}
+		size, err := decimal.NewFromString(v)
+		if err != nil || size.IsNegative() {
+			return decimal.Decimal{}, decimal.Decimal{}, errors.Errorf("invalid child size %s", v)
+		}
+		sizes[i] = size
+	}
+	if sizes[1].IsPositive() && sizes[1].LessThan(sizes[0]) {
+		return decimal.Decimal{}, decimal.Decimal{}, errors.Errorf("max child %s is less than min child %s", max, min)
+	}
+	return sizes[0], sizes[1], nil
+}
+
+// POVChildSize return size of next child order keeping filled quantity at
+// rate of market volume, zero means no child order should be sent
+func POVChildSize(rate, marketVolume, filled, remaining, minChild, maxChild decimal.Decimal) decimal.Decimal {
+	size := rate.Mul(marketVolume).Sub(filled)
+	size = decimal.Min(size, remaining)
+	if maxChild.IsPositive() {
+		size = decimal.Min(size, maxChild)
+	}
+	// the last child may be smaller than min child size
+	if size.LessThan(minChild) && size.LessThan(remaining) {
+		return decimal.Decimal{}
+	}
+	if !size.IsPositive() {
+		return decimal.Decimal{}
+	}
+	return size
+}
+
+func executePOV(c *cli.Context) error {
+	rate := decimal.NewFromFloat(StrToPct(c.String("rate")))
+	minChild, maxChild, err := ParseChildSizes(c.String("min-child"), c.String("max-child"))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	deadline := time.Now().Add(c.Duration("deadline"))
+	interval := c.Duration("interval")
+	reportPath := c.String("report")
+	if !rate.IsPositive() {
+		return errors.New("rate must be positive")
+	}
+	executors, err := newExecutors(c, "pov")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(executors) == 0 {
+		return print(executionReports(executors))
+	}
+	reports := executionReports(executors)
+	// rate is shared between accounts
+	accountRate := rate.Div(decimal.NewFromInt(int64(len(executors))))
+
+	var mu sync.Mutex
+	marketVolume := decimal.Decimal{}
+	doneC, wsStopC, err := binance.WsAggTradeServe(c.String("symbol"), func(event *binance.WsAggTradeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		marketVolume = marketVolume.Add(decimal.RequireFromString(event.Quantity))
+	}, func(err error) {
+		log.Printf("trade stream error: %s", err)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer func() {
+		close(wsStopC)
+		<-doneC
+	}()
+
+	stopC := make(chan os.Signal, 1)
+	signal.Notify(stopC, os.Interrupt, syscall.SIGTERM)
+	for slice := 0; time.Now().Before(deadline); slice++ {
+		if !sleepUntil(time.Now().Add(interval), stopC) {
+			break
+		}
+		mu.Lock()
+		volume := marketVolume
+		mu.Unlock()
+		finished := true
+		for _, e := range executors {
+			if !e.remaining().IsPositive() {
+				continue
+			}
+			finished = false
+			size := POVChildSize(accountRate, volume, e.filled, e.remaining(), minChild, maxChild)
+			if size.IsZero() {
+				continue
+			}
+			s := e.sendChild(slice, size, false)
+			log.Printf("%s: market volume %s, filled %s of %s %s", e.account.Name, volume, s.Filled, s.Quantity, s.Error)
+		}
+		if reportPath != "" {
+			if err := writeJSONFile(reportPath, reports); err != nil {
+				log.Printf("failed to write report: %s", err)
+			}
+		}
+		if finished {
+			break
+		}
+	}
+	if err := print(reports); err != nil {
+		return errors.Trace(err)
+	}
+	return underfilled(executors)
+}
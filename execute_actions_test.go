@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPOVChildSize(t *testing.T) {
+	assert := assert.New(t)
+	d := decimal.RequireFromString
+	tests := []struct {
+		name         string
+		marketVolume string
+		filled       string
+		remaining    string
+		minChild     string
+		maxChild     string
+		expect       string
+	}{
+		{
+			name:         "test with participation below rate",
+			marketVolume: "1000",
+			filled:       "50",
+			remaining:    "500",
+			minChild:     "1",
+			maxChild:     "0",
+			expect:       "50",
+		},
+		{
+			name:         "test with participation above rate",
+			marketVolume: "1000",
+			filled:       "120",
+			remaining:    "500",
+			minChild:     "1",
+			maxChild:     "0",
+			expect:       "0",
+		},
+		{
+			name:         "test with max child",
+			marketVolume: "1000",
+			filled:       "0",
+			remaining:    "500",
+			minChild:     "1",
+			maxChild:     "30",
+			expect:       "30",
+		},
+		{
+			name:         "test with min child",
+			marketVolume: "1000",
+			filled:       "95",
+			remaining:    "500",
+			minChild:     "10",
+			maxChild:     "0",
+			expect:       "0",
+		},
+		{
+			name:         "test with last child under min child",
+			marketVolume: "1000",
+			filled:       "95",
+			remaining:    "3",
+			minChild:     "10",
+			maxChild:     "0",
+			expect:       "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := POVChildSize(d("0.1"), d(tt.marketVolume), d(tt.filled), d(tt.remaining), d(tt.minChild), d(tt.maxChild))
+			assert.Equal(tt.expect, size.String())
+		})
+	}
+}
+
+func TestTWAPSchedule(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	tests := []struct {
+		randomize float64
+		rnd       float64
+		offsets   []time.Duration
+	}{
+		{0, 0, []time.Duration{0, 10 * time.Minute, 20 * time.Minute, 30 * time.Minute}},
+		{0.5, 0.5, []time.Duration{0, 10 * time.Minute, 20 * time.Minute, 30 * time.Minute}},
+		// max jitter is randomize of half of the interval, the first slice is not shifted
+		{0.5, 0, []time.Duration{0, 7*time.Minute + 30*time.Second, 17*time.Minute + 30*time.Second, 27*time.Minute + 30*time.Second}},
+		{1, 0, []time.Duration{0, 5 * time.Minute, 15 * time.Minute, 25 * time.Minute}},
+	}
+	for _, test := range tests {
+		schedule := TWAPSchedule(start, 40*time.Minute, 4, test.randomize, func() float64 { return test.rnd })
+		var offsets []time.Duration
+		for _, at := range schedule {
+			offsets = append(offsets, at.Sub(start))
+		}
+		assert.Equal(t, test.offsets, offsets, "randomize %v rnd %v", test.randomize, test.rnd)
+	}
+
+	// jitter stays within half of the interval for any random value
+	for _, rnd := range []float64{0, 0.25, 0.75, 0.999999} {
+		schedule := TWAPSchedule(start, 40*time.Minute, 4, 1, func() float64 { return rnd })
+		for i := 1; i < len(schedule); i++ {
+			shift := schedule[i].Sub(start.Add(time.Duration(i) * 10 * time.Minute))
+			assert.True(t, shift >= -5*time.Minute && shift <= 5*time.Minute, "shift %s", shift)
+		}
+	}
+}
+
+func TestTWAPSliceAmount(t *testing.T) {
+	d := decimal.RequireFromString
+	tests := []struct {
+		remaining string
+		slices    int
+		i         int
+		expected  string
+	}{
+		{"100", 4, 0, "25"},
+		{"75", 4, 1, "25"},
+		// slice 1 filled 10 of 25, the shortfall is spread over slices 2 and 3
+		{"65", 4, 2, "32.5"},
+		// last slice sends everything remaining
+		{"40", 4, 3, "40"},
+		{"10", 1, 0, "10"},
+	}
+	for _, test := range tests {
+		amount := TWAPSliceAmount(d(test.remaining), test.slices, test.i)
+		assert.Equal(t, test.expected, amount.String(), "%+v", test)
+	}
+}
+
+func TestBookPrice(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("20", BookPrice("BUY", "20", "21", true))
+	assert.Equal("21", BookPrice("BUY", "20", "21", false))
+	assert.Equal("21", BookPrice("SELL", "20", "21", true))
+	assert.Equal("20", BookPrice("SELL", "20", "21", false))
+}
+
+func TestParseChildSizes(t *testing.T) {
+	tests := []struct {
+		min, max         string
+		wantMin, wantMax string
+		err              bool
+	}{
+		{"", "", "0", "0", false},
+		{"0.1", "", "0.1", "0", false},
+		{"0.1", "2", "0.1", "2", false},
+		{"abc", "", "", "", true},
+		{"", "-1", "", "", true},
+		{"2", "1", "", "", true},
+	}
+	for _, test := range tests {
+		min, max, err := ParseChildSizes(test.min, test.max)
+		if test.err {
+			assert.Error(t, err, "%+v", test)
+			continue
+		}
+		assert.NoError(t, err, "%+v", test)
+		assert.Equal(t, test.wantMin, min.String(), "%+v", test)
+		assert.Equal(t, test.wantMax, max.String(), "%+v", test)
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+)
+
+// ListFuturesOpenOrders list USDT-M futures open orders
+func (account *Account) ListFuturesOpenOrders(symbol string) ([]*futures.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Futures.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orders, nil
+}
+
+// CancelFuturesOpenOrders cancel all USDT-M futures open orders of symbol
+func (account *Account) CancelFuturesOpenOrders(symbol string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	err := account.Futures.NewCancelAllOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ListFuturesPositions list USDT-M futures positions
+func (account *Account) ListFuturesPositions() ([]*futures.PositionRisk, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	positions, err := account.Futures.NewGetPositionRiskService().Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return positions, nil
+}
+
+// ListFuturesBalances list USDT-M futures balances
+func (account *Account) ListFuturesBalances() ([]*futures.Balance, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	balances, err := account.Futures.NewGetBalanceService().Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return balances, nil
+}
+
+// GetFuturesAccount get USDT-M futures account
+func (account *Account) GetFuturesAccount() (*futures.Account, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.Futures.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// CloseFuturesPosition close USDT-M futures position with market order
+func (account *Account) CloseFuturesPosition(symbol string, side futures.SideType,
+	positionSide futures.PositionSideType, quantity string) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Futures.NewCreateOrderService().Symbol(symbol).Side(side).
+		Type(futures.OrderTypeMarket).Quantity(quantity).PositionSide(positionSide)
+	// reduce only is rejected in hedge mode where position side is set
+	if positionSide == futures.PositionSideTypeBoth {
+		service = service.ReduceOnly(true)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.OrderID, nil
+}
+
+// ListDeliveryOpenOrders list COIN-M futures open orders
+func (account *Account) ListDeliveryOpenOrders(symbol string) ([]*delivery.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Delivery.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orders, nil
+}
+
+// CancelDeliveryOpenOrders cancel all COIN-M futures open orders of symbol
+func (account *Account) CancelDeliveryOpenOrders(symbol string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	err := account.Delivery.NewCancelAllOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ListDeliveryPositions list COIN-M futures positions
+func (account *Account) ListDeliveryPositions() ([]*delivery.PositionRisk, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	positions, err := account.Delivery.NewGetPositionRiskService().Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return positions, nil
+}
+
+// CloseDeliveryPosition close COIN-M futures position with market order
+func (account *Account) CloseDeliveryPosition(symbol string, side delivery.SideType,
+	positionSide delivery.PositionSideType, quantity string) (int64, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Delivery.NewCreateOrderService().Symbol(symbol).Side(side).
+		Type(delivery.OrderTypeMarket).Quantity(quantity).PositionSide(positionSide)
+	// reduce only is rejected in hedge mode where position side is set
+	if positionSide == delivery.PositionSideTypeBoth {
+		service = service.ReduceOnly(true)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.OrderID, nil
+}
+
+// ListFuturesSymbols list USDT-M futures symbols
+func (account *Account) ListFuturesSymbols() (map[string]futures.Symbol, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	info, err := account.Futures.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ret := make(map[string]futures.Symbol)
+	for _, symbol := range info.Symbols {
+		ret[symbol.Symbol] = symbol
+	}
+	return ret, nil
+}
+
+// CreateFuturesOrder create USDT-M futures order, optional parameters are
+// only sent when not empty
+func (account *Account) CreateFuturesOrder(o *FuturesOrder) (*futures.CreateOrderResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Futures.NewCreateOrderService().Symbol(o.Symbol).Side(futures.SideType(o.Side)).
+		Type(futures.OrderType(o.Type)).Quantity(o.Quantity).PositionSide(futures.PositionSideType(o.PositionSide))
+	if o.Price != "" {
+		service = service.Price(o.Price)
+	}
+	if o.TimeInForce != "" {
+		service = service.TimeInForce(futures.TimeInForceType(o.TimeInForce))
+	}
+	if o.StopPrice != "" {
+		service = service.StopPrice(o.StopPrice)
+	}
+	if o.WorkingType != "" {
+		service = service.WorkingType(futures.WorkingType(o.WorkingType))
+	}
+	if o.ActivationPrice != "" {
+		service = service.ActivationPrice(o.ActivationPrice)
+	}
+	if o.CallbackRate != "" {
+		service = service.CallbackRate(o.CallbackRate)
+	}
+	if o.ReduceOnly {
+		service = service.ReduceOnly(true)
+	}
+	// quantity of closing position order is empty, the service always sends
+	// the quantity parameter
+	if closeOnTrigger(o) {
+		service = service.ClosePosition(true)
+	}
+	if o.ClientOrderID != "" {
+		service = service.NewClientOrderID(o.ClientOrderID)
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// CancelFuturesOrder cancel USDT-M futures open order
+func (account *Account) CancelFuturesOrder(symbol string, orderID int64) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	_, err := account.Futures.NewCancelOrderService().Symbol(symbol).OrderID(orderID).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ListFuturesOrders list all USDT-M futures orders of symbol
+func (account *Account) ListFuturesOrders(symbol string, limit int) ([]*futures.Order, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	service := account.Futures.NewListOrdersService().Symbol(symbol)
+	if limit > 0 {
+		service = service.Limit(limit)
+	}
+	orders, err := service.Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return orders, nil
+}
+
+// GetFuturesMarkPrice get mark price and funding rate of USDT-M futures symbol
+func (account *Account) GetFuturesMarkPrice(symbol string) (*futures.PremiumIndex, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.Futures.NewPremiumIndexService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// ChangeFuturesLeverage change initial leverage of USDT-M futures symbol
+func (account *Account) ChangeFuturesLeverage(symbol string, leverage int) (*futures.SymbolLeverage, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.Futures.NewChangeLeverageService().Symbol(symbol).Leverage(leverage).Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res, nil
+}
+
+// ListFuturesLeverageBrackets list notional brackets and max leverage of
+// USDT-M futures symbol
+func (account *Account) ListFuturesLeverageBrackets(symbol string) ([]futures.Bracket, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.Futures.NewGetLeverageBracketService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, bracket := range res {
+		if bracket.Symbol == symbol {
+			return bracket.Brackets, nil
+		}
+	}
+	return nil, errors.Errorf("leverage brackets of %s not found", symbol)
+}
+
+// ChangeFuturesMarginType change margin type of USDT-M futures symbol
+func (account *Account) ChangeFuturesMarginType(symbol string, marginType futures.MarginType) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	err := account.Futures.NewChangeMarginTypeService().Symbol(symbol).MarginType(marginType).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// GetFuturesPositionMode return true in hedge mode, false in one-way mode
+func (account *Account) GetFuturesPositionMode() (bool, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	res, err := account.Futures.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return res.DualSidePosition, nil
+}
+
+// ChangeFuturesPositionMode change to hedge mode if dual side, or to one-way mode
+func (account *Account) ChangeFuturesPositionMode(dualSide bool) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	err := account.Futures.NewChangePositionModeService().DualSide(dualSide).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// UpdateFuturesPositionMargin add margin to isolated position with action type
+// 1, or reduce it with action type 2
+func (account *Account) UpdateFuturesPositionMargin(symbol, positionSide, amount string, actionType int) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	err := account.Futures.NewUpdatePositionMarginService().Symbol(symbol).
+		PositionSide(futures.PositionSideType(positionSide)).Amount(amount).Type(actionType).Do(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// futuresIncomePageSize is the max number of records of one income history page
+const futuresIncomePageSize = 1000
+
+// ListFuturesIncomeBetween list USDT-M futures income history of symbol and
+// income type between start and end time in milliseconds, empty symbol or
+// income type list all of them
+func (account *Account) ListFuturesIncomeBetween(symbol, incomeType string, startTime, endTime int64) ([]*futures.IncomeHistory, error) {
+	var incomes []*futures.IncomeHistory
+	seen := make(map[string]bool)
+	for startTime < endTime {
+		ctx, cancel := newContext()
+		service := account.Futures.NewGetIncomeHistoryService().StartTime(startTime).EndTime(endTime).
+			Limit(futuresIncomePageSize)
+		if symbol != "" {
+			service = service.Symbol(symbol)
+		}
+		if incomeType != "" {
+			service = service.IncomeType(incomeType)
+		}
+		res, err := service.Do(ctx)
+		cancel()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, income := range res {
+			// next page starts at time of the last record, which is returned again
+			key := fmt.Sprintf("%d_%s_%s_%s", income.TranID, income.IncomeType, income.Asset, income.Symbol)
+			if !seen[key] {
+				seen[key] = true
+				incomes = append(incomes, income)
+			}
+		}
+		if len(res) < futuresIncomePageSize {
+			break
+		}
+		startTime, err = nextIncomePage(res, startTime)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return incomes, nil
+}
+
+// nextIncomePage return start time of the page after a full page, which is
+// time of its last record since the API pages by time only. A full page of
+// records at one millisecond can not be paged without skipping records of
+// that millisecond, so it is an error
+func nextIncomePage(page []*futures.IncomeHistory, startTime int64) (int64, error) {
+	next := page[len(page)-1].Time
+	if next <= startTime {
+		return 0, errors.Errorf("more than %d income records at %d can not be paged by time, narrow it by symbol or income type",
+			len(page), startTime)
+	}
+	return next, nil
+}
+
+// fundingRatePageSize is the max number of records of one funding rate page
+const fundingRatePageSize = 1000
+
+// fundingRateRetries is the number of retries of a rate limited funding rate
+// request, waiting fundingRateBackoff doubled by every retry
+const (
+	fundingRateRetries = 3
+	fundingRateBackoff = 10 * time.Second
+)
+
+// isRateLimited check if request is rejected by request weight limit
+func isRateLimited(err error) bool {
+	apiErr, ok := errors.Cause(err).(*common.APIError)
+	return ok && apiErr.Code == -1003
+}
+
+// ListFundingRatesBetween list USDT-M futures funding rate history of symbol
+// between start and end time in milliseconds, rate limited requests are
+// retried with backoff
+func (account *Account) ListFundingRatesBetween(symbol string, startTime, endTime int64) ([]*futures.FundingRate, error) {
+	var rates []*futures.FundingRate
+	for startTime < endTime {
+		var res []*futures.FundingRate
+		err := retryFundingRate(func() error {
+			ctx, cancel := newContext()
+			defer cancel()
+			var err error
+			res, err = account.Futures.NewFundingRateService().Symbol(symbol).StartTime(startTime).EndTime(endTime).
+				Limit(fundingRatePageSize).Do(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		rates = append(rates, res...)
+		if len(res) < fundingRatePageSize {
+			break
+		}
+		startTime = res[len(res)-1].FundingTime + 1
+	}
+	return rates, nil
+}
+
+// LatestFundingRates list latest limit funding rates of USDT-M perpetual
+// symbol sorted by funding time
+func (account *Account) LatestFundingRates(symbol string, limit int) ([]*futures.FundingRate, error) {
+	var rates []*futures.FundingRate
+	err := retryFundingRate(func() error {
+		ctx, cancel := newContext()
+		defer cancel()
+		var err error
+		rates, err = account.Futures.NewFundingRateService().Symbol(symbol).Limit(limit).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].FundingTime < rates[j].FundingTime
+	})
+	return rates, nil
+}
+
+// retryFundingRate run funding rate request, retrying it with backoff while
+// it is rate limited
+func retryFundingRate(do func() error) error {
+	backoff := fundingRateBackoff
+	for retry := 0; ; retry++ {
+		err := do()
+		if err == nil || !isRateLimited(err) || retry >= fundingRateRetries {
+			return errors.Trace(err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
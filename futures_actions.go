@@ -0,0 +1,157 @@
+package main
+
+import (
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// FuturesAccount define balances and totals of USDT-M futures account
+type FuturesAccount struct {
+	Name                  string             `json:"name"`
+	Balances              []*futures.Balance `json:"balances"`
+	TotalWalletBalance    string             `json:"total_wallet_balance"`
+	TotalUnrealizedProfit string             `json:"total_unrealized_profit"`
+	TotalMarginBalance    string             `json:"total_margin_balance"`
+	TotalInitialMargin    string             `json:"total_initial_margin"`
+	TotalMaintMargin      string             `json:"total_maint_margin"`
+}
+
+// FuturesPositionTotal define sum of positions of symbol and side across accounts
+type FuturesPositionTotal struct {
+	PositionAmt      decimal.Decimal `json:"position_amt"`
+	Notional         decimal.Decimal `json:"notional"`
+	UnRealizedProfit decimal.Decimal `json:"unrealized_profit"`
+}
+
+// SumFuturesPositions sum positions by symbol and position side, notional is
+// valued at mark price
+func SumFuturesPositions(positions []*futures.PositionRisk, totals map[string]*FuturesPositionTotal) {
+	for _, position := range positions {
+		key := position.Symbol + "_" + position.PositionSide
+		total, ok := totals[key]
+		if !ok {
+			total = new(FuturesPositionTotal)
+			totals[key] = total
+		}
+		amount := decimal.RequireFromString(position.PositionAmt)
+		total.PositionAmt = total.PositionAmt.Add(amount)
+		total.Notional = total.Notional.Add(amount.Mul(decimal.RequireFromString(position.MarkPrice)))
+		total.UnRealizedProfit = total.UnRealizedProfit.Add(decimal.RequireFromString(position.UnRealizedProfit))
+	}
+}
+
+func listFuturesBalances(c *cli.Context) error {
+	assets := c.StringSlice("assets")
+	total := c.Bool("total")
+
+	return accountsDo(func(account *Account) (interface{}, error) {
+		balances, err := account.ListFuturesBalances()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		futuresAccount, err := account.GetFuturesAccount()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var l []*futures.Balance
+		for _, b := range balances {
+			if len(assets) > 0 && !StrContains(assets, b.Asset) {
+				continue
+			}
+			if len(assets) == 0 && decimal.RequireFromString(b.Balance).IsZero() {
+				continue
+			}
+			l = append(l, b)
+		}
+		return &FuturesAccount{
+			Name:                  account.Name,
+			Balances:              l,
+			TotalWalletBalance:    futuresAccount.TotalWalletBalance,
+			TotalUnrealizedProfit: futuresAccount.TotalUnrealizedProfit,
+			TotalMarginBalance:    futuresAccount.TotalMarginBalance,
+			TotalInitialMargin:    futuresAccount.TotalInitialMargin,
+			TotalMaintMargin:      futuresAccount.TotalMaintMargin,
+		}, nil
+	}, func(results map[string]interface{}) (interface{}, error) {
+		if !total {
+			return results, nil
+		}
+		totalWalletBalance := decimal.Decimal{}
+		totalUnrealizedProfit := decimal.Decimal{}
+		totalMarginBalance := decimal.Decimal{}
+		m := make(map[string]map[string]decimal.Decimal)
+		for _, res := range results {
+			account, ok := res.(*FuturesAccount)
+			if !ok {
+				continue
+			}
+			totalWalletBalance = totalWalletBalance.Add(decimal.RequireFromString(account.TotalWalletBalance))
+			totalUnrealizedProfit = totalUnrealizedProfit.Add(decimal.RequireFromString(account.TotalUnrealizedProfit))
+			totalMarginBalance = totalMarginBalance.Add(decimal.RequireFromString(account.TotalMarginBalance))
+			for _, b := range account.Balances {
+				asset, ok := m[b.Asset]
+				if !ok {
+					asset = make(map[string]decimal.Decimal)
+					m[b.Asset] = asset
+				}
+				asset["balance"] = asset["balance"].Add(decimal.RequireFromString(b.Balance))
+				asset["crossUnPnl"] = asset["crossUnPnl"].Add(decimal.RequireFromString(b.CrossUnPnl))
+				asset["availableBalance"] = asset["availableBalance"].Add(decimal.RequireFromString(b.AvailableBalance))
+			}
+		}
+		res := map[string]interface{}{
+			"TotalWalletBalance":    totalWalletBalance,
+			"TotalUnrealizedProfit": totalUnrealizedProfit,
+			"TotalMarginBalance":    totalMarginBalance,
+			"Assets":                m,
+		}
+		return []interface{}{results, res}, nil
+	})
+}
+
+func listFuturesPositions(c *cli.Context) error {
+	symbol := c.String("symbol")
+	all := c.Bool("all")
+	total := c.Bool("total")
+
+	return accountsDo(func(account *Account) (interface{}, error) {
+		positions, err := account.ListFuturesPositions()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var l []*futures.PositionRisk
+		for _, position := range positions {
+			if symbol != "" && position.Symbol != symbol {
+				continue
+			}
+			if !all && decimal.RequireFromString(position.PositionAmt).IsZero() {
+				continue
+			}
+			l = append(l, position)
+		}
+		return l, nil
+	}, func(results map[string]interface{}) (interface{}, error) {
+		if !total {
+			return results, nil
+		}
+		totals := make(map[string]*FuturesPositionTotal)
+		for _, res := range results {
+			positions, ok := res.([]*futures.PositionRisk)
+			if !ok {
+				continue
+			}
+			SumFuturesPositions(positions, totals)
+		}
+		totalUnrealizedProfit := decimal.Decimal{}
+		for _, t := range totals {
+			totalUnrealizedProfit = totalUnrealizedProfit.Add(t.UnRealizedProfit)
+		}
+		res := map[string]interface{}{
+			"Positions":             totals,
+			"TotalUnrealizedProfit": totalUnrealizedProfit,
+		}
+		return []interface{}{results, res}, nil
+	})
+}
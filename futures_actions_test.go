@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSumFuturesPositions(t *testing.T) {
+	assert := assert.New(t)
+	totals := make(map[string]*FuturesPositionTotal)
+	SumFuturesPositions([]*futures.PositionRisk{
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", PositionAmt: "0.5", MarkPrice: "20000", UnRealizedProfit: "100"},
+		{Symbol: "ETHUSDT", PositionSide: "BOTH", PositionAmt: "-2", MarkPrice: "600", UnRealizedProfit: "-10"},
+	}, totals)
+	SumFuturesPositions([]*futures.PositionRisk{
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", PositionAmt: "-0.2", MarkPrice: "20000", UnRealizedProfit: "-5"},
+	}, totals)
+
+	assert.Len(totals, 2)
+	assert.Equal("0.3", totals["BTCUSDT_BOTH"].PositionAmt.String())
+	assert.Equal("6000", totals["BTCUSDT_BOTH"].Notional.String())
+	assert.Equal("95", totals["BTCUSDT_BOTH"].UnRealizedProfit.String())
+	assert.Equal("-1200", totals["ETHUSDT_BOTH"].Notional.String())
+}
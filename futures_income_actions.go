@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// fields which futures income can be grouped by, asset is always grouped
+var incomeGroupFields = []string{"account", "symbol", "type", "day"}
+
+// IncomeRecord define a futures income history record of account
+type IncomeRecord struct {
+	Account string
+	Time    int64
+	Symbol  string
+	Type    string
+	Asset   string
+	Income  decimal.Decimal
+	Info    string
+	TranID  int64
+	TradeID string
+}
+
+// IncomeSummary define sum of futures income of a group
+type IncomeSummary struct {
+	Account string          `json:"account,omitempty"`
+	Symbol  string          `json:"symbol,omitempty"`
+	Type    string          `json:"type,omitempty"`
+	Day     string          `json:"day,omitempty"`
+	Asset   string          `json:"asset"`
+	Income  decimal.Decimal `json:"income"`
+	Count   int             `json:"count"`
+}
+
+// incomeDay return UTC date of time in milliseconds
+func incomeDay(t int64) string {
+	return time.Unix(0, t*int64(time.Millisecond)).UTC().Format("2006-01-02")
+}
+
+// AggregateIncome sum income of records by group fields and asset, summaries
+// are sorted by group fields
+func AggregateIncome(records []IncomeRecord, groupBy []string) []*IncomeSummary {
+	m := make(map[IncomeSummary]*IncomeSummary)
+	for _, r := range records {
+		key := IncomeSummary{Asset: r.Asset}
+		if StrContains(groupBy, "account") {
+			key.Account = r.Account
+		}
+		if StrContains(groupBy, "symbol") {
+			key.Symbol = r.Symbol
+		}
+		if StrContains(groupBy, "type") {
+			key.Type = r.Type
+		}
+		if StrContains(groupBy, "day") {
+			key.Day = incomeDay(r.Time)
+		}
+		summary, ok := m[key]
+		if !ok {
+			summary = &IncomeSummary{Account: key.Account, Symbol: key.Symbol, Type: key.Type, Day: key.Day, Asset: key.Asset}
+			m[key] = summary
+		}
+		summary.Income = summary.Income.Add(r.Income)
+		summary.Count++
+	}
+	summaries := make([]*IncomeSummary, 0, len(m))
+	for _, summary := range m {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		for _, pair := range [][2]string{{a.Account, b.Account}, {a.Day, b.Day}, {a.Symbol, b.Symbol},
+			{a.Type, b.Type}, {a.Asset, b.Asset}} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+	return summaries
+}
+
+// incomeRecords convert income history of account to records
+func incomeRecords(accountName string, incomes []*futures.IncomeHistory) ([]IncomeRecord, error) {
+	records := make([]IncomeRecord, 0, len(incomes))
+	for _, income := range incomes {
+		amount, err := decimal.NewFromString(income.Income)
+		if err != nil {
+			return nil, errors.Errorf("invalid income %s of tran id %d", income.Income, income.TranID)
+		}
+		records = append(records, IncomeRecord{
+			Account: accountName,
+			Time:    income.Time,
+			Symbol:  income.Symbol,
+			Type:    income.IncomeType,
+			Asset:   income.Asset,
+			Income:  amount,
+			Info:    income.Info,
+			TranID:  income.TranID,
+			TradeID: income.TradeID,
+		})
+	}
+	return records, nil
+}
+
+// writeIncomeCSV write income records to csv file, time is in UTC
+func writeIncomeCSV(filePath string, records []IncomeRecord) error {
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []string{
+			r.Account,
+			formatMillis(r.Time),
+			r.Symbol,
+			r.Type,
+			r.Asset,
+			r.Income.String(),
+			r.Info,
+			strconv.FormatInt(r.TranID, 10),
+			r.TradeID,
+		})
+	}
+	header := []string{"account", "time", "symbol", "type", "asset", "income", "info", "tran_id", "trade_id"}
+	return errors.Trace(writeCSVFile(filePath, header, rows))
+}
+
+// writeIncomeSummaryCSV write income summaries to csv file
+func writeIncomeSummaryCSV(filePath string, summaries []*IncomeSummary) error {
+	rows := make([][]string, 0, len(summaries))
+	for _, s := range summaries {
+		rows = append(rows, []string{s.Account, s.Day, s.Symbol, s.Type, s.Asset, s.Income.String(), strconv.Itoa(s.Count)})
+	}
+	header := []string{"account", "day", "symbol", "type", "asset", "income", "count"}
+	return errors.Trace(writeCSVFile(filePath, header, rows))
+}
+
+func futuresIncome(c *cli.Context) error {
+	symbol := c.String("symbol")
+	incomeType := strings.ToUpper(c.String("type"))
+	csvPath := c.String("csv")
+	summaryCSVPath := c.String("summary-csv")
+	var groupBy []string
+	for _, field := range strings.Split(c.String("group-by"), ",") {
+		field = strings.TrimSpace(strings.ToLower(field))
+		if field == "" {
+			continue
+		}
+		if !StrContains(incomeGroupFields, field) {
+			return errors.Errorf("invalid group by field %s, must be in %s", field, strings.Join(incomeGroupFields, ","))
+		}
+		groupBy = append(groupBy, field)
+	}
+	start, end, err := ParseDateRange(c.String("start"), c.String("end"), 7, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	startMillis := start.UnixNano() / int64(time.Millisecond)
+	endMillis := end.UnixNano() / int64(time.Millisecond)
+
+	return accountsDo(func(account *Account) (interface{}, error) {
+		incomes, err := account.ListFuturesIncomeBetween(symbol, incomeType, startMillis, endMillis)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		records, err := incomeRecords(account.Name, incomes)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return records, nil
+	}, func(results map[string]interface{}) (interface{}, error) {
+		var records []IncomeRecord
+		accountErrors := make(map[string]interface{})
+		for name, res := range results {
+			l, ok := res.([]IncomeRecord)
+			if !ok {
+				accountErrors[name] = res
+				continue
+			}
+			records = append(records, l...)
+		}
+		sort.SliceStable(records, func(i, j int) bool {
+			if records[i].Time != records[j].Time {
+				return records[i].Time < records[j].Time
+			}
+			return records[i].Account < records[j].Account
+		})
+		// csv of part of accounts would be taken as complete
+		if len(accountErrors) > 0 && (csvPath != "" || summaryCSVPath != "") {
+			var l []string
+			for name, res := range accountErrors {
+				l = append(l, fmt.Sprintf("%s: %s", name, resultError(res)))
+			}
+			sort.Strings(l)
+			return nil, errors.Errorf("csv is not written since income of %d accounts failed: %s",
+				len(l), strings.Join(l, "; "))
+		}
+		summaries := AggregateIncome(records, groupBy)
+		if csvPath != "" {
+			if err := writeIncomeCSV(csvPath, records); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if summaryCSVPath != "" {
+			if err := writeIncomeSummaryCSV(summaryCSVPath, summaries); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		totals := make(map[string]decimal.Decimal)
+		for _, r := range records {
+			totals[r.Asset] = totals[r.Asset].Add(r.Income)
+		}
+		res := map[string]interface{}{
+			"Summary": summaries,
+			"Totals":  totals,
+			"Records": len(records),
+		}
+		if len(accountErrors) > 0 {
+			res["Errors"] = accountErrors
+		}
+		return res, nil
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextIncomePage(t *testing.T) {
+	assert := assert.New(t)
+	page := []*futures.IncomeHistory{{Time: 100}, {Time: 150}, {Time: 150}}
+	next, err := nextIncomePage(page, 100)
+	assert.NoError(err)
+	assert.Equal(int64(150), next)
+
+	// records of one millisecond filling a page can not be paged by time
+	page = []*futures.IncomeHistory{{Time: 150}, {Time: 150}}
+	_, err = nextIncomePage(page, 150)
+	assert.Error(err)
+}
+
+func TestAggregateIncome(t *testing.T) {
+	day := int64(24 * 3600 * 1000)
+	records := []IncomeRecord{
+		{Account: "a", Time: 0, Symbol: "BTCUSDT", Type: "FUNDING_FEE", Asset: "USDT", Income: decimal.RequireFromString("-1.5")},
+		{Account: "a", Time: 1000, Symbol: "BTCUSDT", Type: "FUNDING_FEE", Asset: "USDT", Income: decimal.RequireFromString("0.5")},
+		{Account: "b", Time: day, Symbol: "BTCUSDT", Type: "FUNDING_FEE", Asset: "USDT", Income: decimal.RequireFromString("2")},
+		{Account: "b", Time: day, Symbol: "ETHUSDT", Type: "REALIZED_PNL", Asset: "USDT", Income: decimal.RequireFromString("10")},
+		{Account: "b", Time: day, Symbol: "", Type: "TRANSFER", Asset: "BNB", Income: decimal.RequireFromString("1")},
+	}
+
+	tests := []struct {
+		groupBy  []string
+		expected []IncomeSummary
+	}{
+		{nil, []IncomeSummary{
+			{Asset: "BNB", Income: decimal.RequireFromString("1"), Count: 1},
+			{Asset: "USDT", Income: decimal.RequireFromString("11"), Count: 4},
+		}},
+		{[]string{"symbol", "type"}, []IncomeSummary{
+			{Type: "TRANSFER", Asset: "BNB", Income: decimal.RequireFromString("1"), Count: 1},
+			{Symbol: "BTCUSDT", Type: "FUNDING_FEE", Asset: "USDT", Income: decimal.RequireFromString("1"), Count: 3},
+			{Symbol: "ETHUSDT", Type: "REALIZED_PNL", Asset: "USDT", Income: decimal.RequireFromString("10"), Count: 1},
+		}},
+		{[]string{"account", "day"}, []IncomeSummary{
+			{Account: "a", Day: "1970-01-01", Asset: "USDT", Income: decimal.RequireFromString("-1"), Count: 2},
+			{Account: "b", Day: "1970-01-02", Asset: "BNB", Income: decimal.RequireFromString("1"), Count: 1},
+			{Account: "b", Day: "1970-01-02", Asset: "USDT", Income: decimal.RequireFromString("12"), Count: 2},
+		}},
+	}
+	for _, test := range tests {
+		summaries := AggregateIncome(records, test.groupBy)
+		if !assert.Len(t, summaries, len(test.expected), "%v", test.groupBy) {
+			continue
+		}
+		for i, expected := range test.expected {
+			s := summaries[i]
+			assert.Equal(t, expected.Account, s.Account)
+			assert.Equal(t, expected.Day, s.Day)
+			assert.Equal(t, expected.Symbol, s.Symbol)
+			assert.Equal(t, expected.Type, s.Type)
+			assert.Equal(t, expected.Asset, s.Asset)
+			assert.Equal(t, expected.Income.String(), s.Income.String())
+			assert.Equal(t, expected.Count, s.Count)
+		}
+	}
+}
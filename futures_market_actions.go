@@ -0,0 +1,284 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// defaultFundingInterval is the funding interval assumed when it can not be
+// derived from funding history
+const defaultFundingInterval = 8 * time.Hour
+
+// fundingRateThrottle is the wait between funding rate history requests of
+// symbols, which share 500 requests per 5 minutes of an IP
+const fundingRateThrottle = 600 * time.Millisecond
+
+// FundingRateStat define funding rate of perpetual symbol, which is the rate
+// of one funding interval or the average of a time range
+type FundingRateStat struct {
+	Symbol         string          `json:"symbol"`
+	FundingRate    decimal.Decimal `json:"funding_rate"`
+	AnnualizedRate decimal.Decimal `json:"annualized_rate"`
+	FundingTime    string          `json:"funding_time,omitempty"`
+	// FundingInterval is derived from funding times and annualizes the rate
+	FundingInterval string `json:"funding_interval,omitempty"`
+	// Count is the number of funding intervals averaged
+	Count int `json:"count,omitempty"`
+}
+
+// MarkPriceStat define current mark price and funding of perpetual symbol,
+// basis is relative to spot price
+type MarkPriceStat struct {
+	Symbol         string          `json:"symbol"`
+	MarkPrice      decimal.Decimal `json:"mark_price"`
+	SpotPrice      string          `json:"spot_price,omitempty"`
+	Basis          string          `json:"basis,omitempty"`
+	FundingRate    decimal.Decimal `json:"funding_rate"`
+	AnnualizedRate decimal.Decimal `json:"annualized_rate"`
+	// FundingInterval is derived from the latest two funding times
+	FundingInterval string `json:"funding_interval"`
+	NextFundingTime string `json:"next_funding_time"`
+}
+
+// AnnualizedFundingRate return funding rate of one interval scaled to a year
+func AnnualizedFundingRate(rate decimal.Decimal, interval time.Duration) decimal.Decimal {
+	if interval <= 0 {
+		interval = defaultFundingInterval
+	}
+	intervals := decimal.NewFromInt(int64(365 * 24 * time.Hour)).Div(decimal.NewFromInt(int64(interval)))
+	return rate.Mul(intervals)
+}
+
+// FundingInterval return interval between the latest two funding times of
+// rates sorted by time, rounded to hour. defaultFundingInterval is returned
+// if there are less than two rates
+func FundingInterval(rates []*futures.FundingRate) time.Duration {
+	if len(rates) < 2 {
+		return defaultFundingInterval
+	}
+	gap := time.Duration(rates[len(rates)-1].FundingTime-rates[len(rates)-2].FundingTime) * time.Millisecond
+	interval := gap.Round(time.Hour)
+	if interval < time.Hour {
+		return defaultFundingInterval
+	}
+	return interval
+}
+
+// Basis return premium of mark price over spot price relative to spot price
+func Basis(markPrice, spotPrice decimal.Decimal) decimal.Decimal {
+	if !spotPrice.IsPositive() {
+		return decimal.Decimal{}
+	}
+	return markPrice.Sub(spotPrice).Div(spotPrice)
+}
+
+// AverageFundingRate return average funding rate of history of symbol
+func AverageFundingRate(symbol string, rates []*futures.FundingRate) *FundingRateStat {
+	sum := decimal.Decimal{}
+	for _, rate := range rates {
+		sum = sum.Add(decimal.RequireFromString(rate.FundingRate))
+	}
+	average := decimal.Decimal{}
+	if len(rates) > 0 {
+		average = sum.Div(decimal.NewFromInt(int64(len(rates))))
+	}
+	interval := FundingInterval(rates)
+	return &FundingRateStat{
+		Symbol:          symbol,
+		FundingRate:     average,
+		AnnualizedRate:  AnnualizedFundingRate(average, interval),
+		FundingInterval: interval.String(),
+		Count:           len(rates),
+	}
+}
+
+// SortMarkPrices sort mark prices by symbol, funding rate or basis,
+// descending if desc, mark prices without basis are sorted last
+func SortMarkPrices(stats []*MarkPriceStat, field string, desc bool) error {
+	var less func(a, b *MarkPriceStat) bool
+	switch field {
+	case "symbol":
+		less = func(a, b *MarkPriceStat) bool { return a.Symbol < b.Symbol }
+	case "funding":
+		less = func(a, b *MarkPriceStat) bool { return a.FundingRate.LessThan(b.FundingRate) }
+	case "basis":
+		less = func(a, b *MarkPriceStat) bool {
+			return decimal.RequireFromString(a.Basis).LessThan(decimal.RequireFromString(b.Basis))
+		}
+	default:
+		return errors.Errorf("invalid sort field %s, must be symbol, funding or basis", field)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if field == "basis" && (a.Basis == "" || b.Basis == "") {
+			return a.Basis != "" && b.Basis == ""
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
+
+// SortFundingRates sort funding rates by symbol or funding rate, descending if desc
+func SortFundingRates(stats []*FundingRateStat, field string, desc bool) error {
+	var less func(a, b *FundingRateStat) bool
+	switch field {
+	case "symbol":
+		less = func(a, b *FundingRateStat) bool { return a.Symbol < b.Symbol }
+	case "funding":
+		less = func(a, b *FundingRateStat) bool { return a.FundingRate.LessThan(b.FundingRate) }
+	default:
+		return errors.Errorf("invalid sort field %s, must be symbol or funding", field)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if desc {
+			return less(stats[j], stats[i])
+		}
+		return less(stats[i], stats[j])
+	})
+	return nil
+}
+
+// perpetualSymbols return the symbol if set, or all trading perpetual symbols
+func (account *Account) perpetualSymbols(symbol string) ([]string, error) {
+	if symbol != "" {
+		return []string{symbol}, nil
+	}
+	if err := account.loadFuturesSymbols(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var l []string
+	for _, info := range futuresSymbols {
+		if info.ContractType == futures.ContractTypePerpetual && info.Status == "TRADING" {
+			l = append(l, info.Symbol)
+		}
+	}
+	sort.Strings(l)
+	return l, nil
+}
+
+func listFundingRates(c *cli.Context) error {
+	symbol := c.String("symbol")
+	sortField := strings.ToLower(c.String("sort"))
+	desc := c.Bool("desc")
+	if err := SortFundingRates(nil, sortField, desc); err != nil {
+		return errors.Trace(err)
+	}
+	start, end, err := ParseDateRange(c.String("start"), c.String("end"), 7, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	startMillis := start.UnixNano() / int64(time.Millisecond)
+	endMillis := end.UnixNano() / int64(time.Millisecond)
+
+	return runOnce(func(account *Account) (interface{}, error) {
+		// history of one symbol is listed, or average of every perpetual symbol
+		if symbol != "" {
+			rates, err := account.ListFundingRatesBetween(symbol, startMillis, endMillis)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			interval := FundingInterval(rates)
+			var history []*FundingRateStat
+			for _, rate := range rates {
+				r := decimal.RequireFromString(rate.FundingRate)
+				history = append(history, &FundingRateStat{
+					Symbol:          rate.Symbol,
+					FundingRate:     r,
+					AnnualizedRate:  AnnualizedFundingRate(r, interval),
+					FundingTime:     formatMillis(rate.FundingTime),
+					FundingInterval: interval.String(),
+				})
+			}
+			return map[string]interface{}{
+				"History": history,
+				"Average": AverageFundingRate(symbol, rates),
+			}, nil
+		}
+		symbols, err := account.perpetualSymbols("")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var stats []*FundingRateStat
+		for i, s := range symbols {
+			if i > 0 {
+				time.Sleep(fundingRateThrottle)
+			}
+			rates, err := account.ListFundingRatesBetween(s, startMillis, endMillis)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			stats = append(stats, AverageFundingRate(s, rates))
+		}
+		if err := SortFundingRates(stats, sortField, desc); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return stats, nil
+	})
+}
+
+func listMarkPrices(c *cli.Context) error {
+	symbol := c.String("symbol")
+	sortField := strings.ToLower(c.String("sort"))
+	desc := c.Bool("desc")
+	if err := SortMarkPrices(nil, sortField, desc); err != nil {
+		return errors.Trace(err)
+	}
+	return runOnce(func(account *Account) (interface{}, error) {
+		symbols, err := account.perpetualSymbols(symbol)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		prices, err := account.ListPrices("")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		spotPrices := make(map[string]string)
+		for _, p := range prices {
+			spotPrices[p.Symbol] = p.Price
+		}
+		var stats []*MarkPriceStat
+		for i, s := range symbols {
+			if i > 0 {
+				time.Sleep(fundingRateThrottle)
+			}
+			rates, err := account.LatestFundingRates(s, 2)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			interval := FundingInterval(rates)
+			index, err := account.GetFuturesMarkPrice(s)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			markPrice := decimal.RequireFromString(index.MarkPrice)
+			rate := decimal.RequireFromString(index.LastFundingRate)
+			stat := &MarkPriceStat{
+				Symbol:          s,
+				MarkPrice:       markPrice,
+				FundingRate:     rate,
+				AnnualizedRate:  AnnualizedFundingRate(rate, interval),
+				FundingInterval: interval.String(),
+				NextFundingTime: formatMillis(index.NextFundingTime),
+			}
+			// spot symbol has the same name as the USDT-M perpetual symbol
+			if spotPrice, ok := spotPrices[s]; ok {
+				stat.SpotPrice = spotPrice
+				stat.Basis = Basis(markPrice, decimal.RequireFromString(spotPrice)).String()
+			}
+			stats = append(stats, stat)
+		}
+		if err := SortMarkPrices(stats, sortField, desc); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return stats, nil
+	})
+}
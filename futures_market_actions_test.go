@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAverageFundingRate(t *testing.T) {
+	assert := assert.New(t)
+	stat := AverageFundingRate("BTCUSDT", []*futures.FundingRate{
+		{FundingRate: "0.0001"}, {FundingRate: "0.0003"}, {FundingRate: "-0.0001"},
+	})
+	assert.Equal("0.0001", stat.FundingRate.String())
+	assert.Equal("0.1095", stat.AnnualizedRate.String())
+	assert.Equal(3, stat.Count)
+	assert.Equal("8h0m0s", stat.FundingInterval)
+
+	hour := int64(time.Hour / time.Millisecond)
+	stat = AverageFundingRate("BTCUSDT", []*futures.FundingRate{
+		{FundingRate: "0.0001", FundingTime: 0}, {FundingRate: "0.0001", FundingTime: 4 * hour},
+	})
+	assert.Equal("0.219", stat.AnnualizedRate.String())
+	assert.Equal("4h0m0s", stat.FundingInterval)
+	assert.True(AverageFundingRate("BTCUSDT", nil).FundingRate.IsZero())
+}
+
+func TestFundingInterval(t *testing.T) {
+	assert := assert.New(t)
+	hour := int64(time.Hour / time.Millisecond)
+	assert.Equal(8*time.Hour, FundingInterval(nil))
+	assert.Equal(8*time.Hour, FundingInterval([]*futures.FundingRate{{FundingTime: 0}}))
+	assert.Equal(8*time.Hour, FundingInterval([]*futures.FundingRate{{FundingTime: 0}, {FundingTime: 8*hour + 3}}))
+	assert.Equal(4*time.Hour, FundingInterval([]*futures.FundingRate{
+		{FundingTime: 0}, {FundingTime: 8 * hour}, {FundingTime: 12*hour + 5},
+	}))
+	assert.Equal(8*time.Hour, FundingInterval([]*futures.FundingRate{{FundingTime: 0}, {FundingTime: 8}}))
+}
+
+func TestAnnualizedFundingRate(t *testing.T) {
+	assert := assert.New(t)
+	rate := decimal.RequireFromString("0.0001")
+	assert.Equal("0.1095", AnnualizedFundingRate(rate, 8*time.Hour).String())
+	assert.Equal("0.219", AnnualizedFundingRate(rate, 4*time.Hour).String())
+	assert.Equal("0.876", AnnualizedFundingRate(rate, time.Hour).String())
+	assert.Equal("0.1095", AnnualizedFundingRate(rate, 0).String())
+}
+
+func TestBasis(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("0.01", Basis(decimal.NewFromInt(101), decimal.NewFromInt(100)).String())
+	assert.Equal("-0.02", Basis(decimal.NewFromInt(98), decimal.NewFromInt(100)).String())
+	assert.True(Basis(decimal.NewFromInt(98), decimal.Decimal{}).IsZero())
+}
+
+func TestSortMarkPrices(t *testing.T) {
+	stats := []*MarkPriceStat{
+		{Symbol: "A", FundingRate: decimal.RequireFromString("0.0002"), Basis: "0.01"},
+		{Symbol: "B", FundingRate: decimal.RequireFromString("-0.0001")},
+		{Symbol: "C", FundingRate: decimal.RequireFromString("0.0001"), Basis: "-0.02"},
+	}
+	symbols := func() string {
+		var s string
+		for _, stat := range stats {
+			s += stat.Symbol
+		}
+		return s
+	}
+	tests := []struct {
+		field    string
+		desc     bool
+		expected string
+	}{
+		{"funding", false, "BCA"},
+		{"funding", true, "ACB"},
+		{"basis", false, "CAB"},
+		{"basis", true, "ACB"},
+		{"symbol", false, "ABC"},
+	}
+	for _, test := range tests {
+		assert.NoError(t, SortMarkPrices(stats, test.field, test.desc))
+		assert.Equal(t, test.expected, symbols(), "%s %v", test.field, test.desc)
+	}
+	assert.Error(t, SortMarkPrices(stats, "volume", false))
+}
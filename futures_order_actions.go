@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var futuresSymbols map[string]futures.Symbol
+
+// FuturesOrder define a computed USDT-M futures order request of account
+type FuturesOrder struct {
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`
+	Type            string `json:"type"`
+	PositionSide    string `json:"position_side"`
+	Quantity        string `json:"quantity"`
+	Price           string `json:"price,omitempty"`
+	StopPrice       string `json:"stop_price,omitempty"`
+	ActivationPrice string `json:"activation_price,omitempty"`
+	CallbackRate    string `json:"callback_rate,omitempty"`
+	WorkingType     string `json:"working_type,omitempty"`
+	TimeInForce     string `json:"time_in_force,omitempty"`
+	ReduceOnly      bool   `json:"reduce_only,omitempty"`
+	// ClosePosition closes whole position when STOP_MARKET or
+	// TAKE_PROFIT_MARKET order triggers, other orders are sent as reduce only
+	// order of current position amount
+	ClosePosition bool   `json:"close_position,omitempty"`
+	Notional      string `json:"notional"`
+	// Sizing describes how quantity is computed, empty for absolute quantity
+	Sizing        string `json:"sizing,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	OrderID       int64  `json:"order_id,omitempty"`
+}
+
+// closeOnTrigger check if order is sent with closePosition, which closes
+// whole position when stop price triggers without fixed quantity
+func closeOnTrigger(o *FuturesOrder) bool {
+	return o.ClosePosition && (o.Type == string(futures.OrderTypeStopMarket) ||
+		o.Type == string(futures.OrderTypeTakeProfitMarket))
+}
+
+// ValidateFuturesOrder check required and forbidden parameters of order type,
+// and decimal format of quantity, prices and callback rate
+func ValidateFuturesOrder(o *FuturesOrder) error {
+	if o.Symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if o.Side != string(futures.SideTypeBuy) && o.Side != string(futures.SideTypeSell) {
+		return errors.Errorf("invalid side %s", o.Side)
+	}
+	switch futures.PositionSideType(o.PositionSide) {
+	case futures.PositionSideTypeBoth:
+	case futures.PositionSideTypeLong, futures.PositionSideTypeShort:
+		if o.ReduceOnly {
+			return errors.New("reduce only is not allowed with position side LONG or SHORT")
+		}
+	default:
+		return errors.Errorf("invalid position side %s", o.PositionSide)
+	}
+	if o.ClosePosition && o.Quantity != "" {
+		return errors.New("quantity is not allowed with close position")
+	}
+	if !o.ClosePosition && o.Quantity == "" {
+		return errors.New("quantity is required")
+	}
+	if closeOnTrigger(o) && o.ReduceOnly {
+		return errors.Errorf("reduce only is not allowed with close position of %s order", o.Type)
+	}
+	if o.Quantity != "" {
+		q, err := decimal.NewFromString(strings.TrimSuffix(o.Quantity, "%"))
+		if err != nil || !q.IsPositive() {
+			return errors.Errorf("invalid quantity %s", o.Quantity)
+		}
+	}
+	for _, param := range []struct{ name, value string }{
+		{"price", o.Price},
+		{"stop price", o.StopPrice},
+		{"activation price", o.ActivationPrice},
+		{"callback rate", o.CallbackRate},
+	} {
+		if param.value == "" {
+			continue
+		}
+		if v, err := decimal.NewFromString(param.value); err != nil || !v.IsPositive() {
+			return errors.Errorf("invalid %s %s", param.name, param.value)
+		}
+	}
+	switch futures.OrderType(o.Type) {
+	case futures.OrderTypeLimit:
+		if o.Price == "" {
+			return errors.Errorf("price is required by %s order", o.Type)
+		}
+	case futures.OrderTypeMarket:
+		if o.Price != "" {
+			return errors.Errorf("price is not allowed with %s order", o.Type)
+		}
+	case futures.OrderTypeStop, futures.OrderTypeTakeProfit:
+		if o.Price == "" || o.StopPrice == "" {
+			return errors.Errorf("price and stop price are required by %s order", o.Type)
+		}
+	case futures.OrderTypeStopMarket, futures.OrderTypeTakeProfitMarket:
+		if o.StopPrice == "" {
+			return errors.Errorf("stop price is required by %s order", o.Type)
+		}
+		if o.Price != "" {
+			return errors.Errorf("price is not allowed with %s order", o.Type)
+		}
+	case futures.OrderTypeTrailingStopMarket:
+		if o.CallbackRate == "" {
+			return errors.Errorf("callback rate is required by %s order", o.Type)
+		}
+		if o.Price != "" {
+			return errors.Errorf("price is not allowed with %s order", o.Type)
+		}
+	default:
+		return errors.Errorf("invalid order type %s", o.Type)
+	}
+	return nil
+}
+
+// FuturesPercentQuantity return quantity of percent of available margin times
+// leverage at price
+func FuturesPercentQuantity(pct float64, available, leverage, price decimal.Decimal) decimal.Decimal {
+	if !price.IsPositive() {
+		return decimal.Decimal{}
+	}
+	return available.Mul(decimal.NewFromFloat(pct)).Mul(leverage).Div(price)
+}
+
+// loadFuturesSymbols load USDT-M futures symbols once
+func (account *Account) loadFuturesSymbols() error {
+	var err error
+	if futuresSymbols == nil {
+		futuresSymbols, err = account.ListFuturesSymbols()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// futuresLotQuantity round amount down to lot size of futures symbol
+func futuresLotQuantity(info futures.Symbol, amount decimal.Decimal) (string, error) {
+	lotSize := info.LotSizeFilter()
+	if lotSize == nil {
+		return "", errors.Errorf("lot size filter of %s not found", info.Symbol)
+	}
+	return AmountToLotSize(amount.String(), lotSize.MinQuantity, lotSize.StepSize, info.QuantityPrecision), nil
+}
+
+// futuresPosition return position of symbol and position side
+func (account *Account) futuresPosition(symbol, positionSide string) (*futures.PositionRisk, error) {
+	positions, err := account.ListFuturesPositions()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, position := range positions {
+		if position.Symbol == symbol && position.PositionSide == positionSide {
+			return position, nil
+		}
+	}
+	return nil, errors.Errorf("position %s %s not found", symbol, positionSide)
+}
+
+// futuresOrderQuantity resolve percent quantity against available margin
+// times leverage, or quantity of current position when closing it. It returns
+// the quantity valued as notional, which is the current position amount of
+// order closing position on trigger, whose quantity is left empty
+func (account *Account) futuresOrderQuantity(o *FuturesOrder, info futures.Symbol, price decimal.Decimal) (decimal.Decimal, error) {
+	if o.ClosePosition {
+		position, err := account.futuresPosition(o.Symbol, o.PositionSide)
+		if err != nil {
+			return decimal.Decimal{}, errors.Trace(err)
+		}
+		amount := decimal.RequireFromString(position.PositionAmt)
+		if amount.IsZero() {
+			return decimal.Decimal{}, errors.Errorf("no position %s %s to close", o.Symbol, o.PositionSide)
+		}
+		if amount.IsPositive() == (o.Side == string(futures.SideTypeBuy)) {
+			return decimal.Decimal{}, errors.Errorf("%s does not close position amount %s", o.Side, amount)
+		}
+		if closeOnTrigger(o) {
+			o.Sizing = fmt.Sprintf("close whole position on trigger, currently %s", amount)
+			return amount.Abs(), nil
+		}
+		o.Quantity = amount.Abs().String()
+		o.Sizing = fmt.Sprintf("close position amount %s", amount)
+		// reduce only is rejected in hedge mode where position side is set
+		o.ReduceOnly = o.PositionSide == string(futures.PositionSideTypeBoth)
+		return amount.Abs(), nil
+	}
+	if !strings.HasSuffix(o.Quantity, "%") {
+		quantity, err := decimal.NewFromString(o.Quantity)
+		if err != nil {
+			return decimal.Decimal{}, errors.Errorf("invalid quantity %s", o.Quantity)
+		}
+		return quantity, nil
+	}
+	balances, err := account.ListFuturesBalances()
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	available := decimal.Decimal{}
+	for _, balance := range balances {
+		if balance.Asset == info.MarginAsset {
+			available = decimal.RequireFromString(balance.AvailableBalance)
+		}
+	}
+	position, err := account.futuresPosition(o.Symbol, o.PositionSide)
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	leverage := decimal.RequireFromString(position.Leverage)
+	amount := FuturesPercentQuantity(StrToPct(o.Quantity), available, leverage, price)
+	quantity, err := futuresLotQuantity(info, amount)
+	if err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	o.Sizing = fmt.Sprintf("%s of available %s %s times leverage %s", o.Quantity, available, info.MarginAsset, leverage)
+	o.Quantity = quantity
+	return decimal.RequireFromString(quantity), nil
+}
+
+// planFuturesOrder compute futures order of account without sending it,
+// notional is valued at price, stop price or mark price
+func (account *Account) planFuturesOrder(order FuturesOrder, tag string) (*FuturesOrder, error) {
+	o := &order
+	if err := account.loadFuturesSymbols(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := futuresSymbols[o.Symbol]
+	if !ok {
+		return nil, errors.Errorf("futures symbol %s not found", o.Symbol)
+	}
+	price := o.Price
+	if price == "" {
+		price = o.StopPrice
+	}
+	if price == "" {
+		markPrice, err := account.GetFuturesMarkPrice(o.Symbol)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		price = markPrice.MarkPrice
+	}
+	quantity, err := account.futuresOrderQuantity(o, info, decimal.RequireFromString(price))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !quantity.IsPositive() {
+		return nil, errors.Errorf("invalid quantity %s", quantity)
+	}
+	o.Notional = quantity.Mul(decimal.RequireFromString(price)).String()
+	o.ClientOrderID = ClientOrderID(tag, account.Name, o.Symbol, o.Side)
+	return o, nil
+}
+
+func futuresCreateOrder(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	order := FuturesOrder{
+		Symbol:          c.String("symbol"),
+		Side:            strings.ToUpper(c.String("side")),
+		Type:            strings.ToUpper(c.String("type")),
+		PositionSide:    strings.ToUpper(c.String("position-side")),
+		Quantity:        c.String("quantity"),
+		Price:           c.String("price"),
+		StopPrice:       c.String("stop-price"),
+		ActivationPrice: c.String("activation-price"),
+		CallbackRate:    c.String("callback-rate"),
+		WorkingType:     strings.ToUpper(c.String("working-type")),
+		ReduceOnly:      c.Bool("reduce-only"),
+		ClosePosition:   c.Bool("close-position"),
+	}
+	tag := c.String("tag")
+	if err := ValidateFuturesOrder(&order); err != nil {
+		return errors.Trace(err)
+	}
+	if err := CheckTag(tag); err != nil {
+		return errors.Trace(err)
+	}
+	if order.Type == string(futures.OrderTypeLimit) || order.Type == string(futures.OrderTypeStop) ||
+		order.Type == string(futures.OrderTypeTakeProfit) {
+		order.TimeInForce = strings.ToUpper(c.String("time-in-force"))
+	}
+	if err := config.CheckSymbol(order.Symbol); err != nil {
+		return errors.Trace(err)
+	}
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			o, err := account.planFuturesOrder(order, tag)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err := config.CheckNotional(account.Name, decimal.RequireFromString(o.Notional)); err != nil {
+				return nil, errors.Trace(err)
+			}
+			return o, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	var numOrders int
+	total := decimal.Decimal{}
+	for _, res := range results {
+		if o, ok := res.(*FuturesOrder); ok {
+			total = total.Add(decimal.RequireFromString(o.Notional))
+			numOrders++
+		}
+	}
+	if err := config.CheckAccounts(numOrders); err != nil {
+		return errors.Trace(err)
+	}
+	if numOrders > 0 {
+		if err := confirm("create futures orders on %d accounts with total notional %s?", numOrders, total); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			o, ok := results[account.Name].(*FuturesOrder)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			res, err := account.CreateFuturesOrder(o)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			o.OrderID = res.OrderID
+			return o, nil
+		})
+}
+
+func futuresCancelOrders(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	orderID := c.Int64("id")
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if err := checkOrderID(symbol, orderID, name); err != nil {
+		return errors.Trace(err)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			if orderID != 0 {
+				return []*futures.Order{{Symbol: symbol, OrderID: orderID}}, nil
+			}
+			orders, err := account.ListFuturesOpenOrders(symbol)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return orders, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	var numOrders, numAccounts int
+	for _, res := range results {
+		if orders, ok := res.([]*futures.Order); ok && len(orders) > 0 {
+			numOrders += len(orders)
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numOrders > 0 {
+		if err := confirm("cancel %d futures orders of %s on %d accounts?", numOrders, symbol, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			cancelingOrders, ok := results[account.Name].([]*futures.Order)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			if len(cancelingOrders) == 0 {
+				return []int64{}, nil
+			}
+			if orderID != 0 {
+				if err := account.CancelFuturesOrder(symbol, orderID); err != nil {
+					return nil, errors.Trace(err)
+				}
+				return []int64{orderID}, nil
+			}
+			if err := account.CancelFuturesOpenOrders(symbol); err != nil {
+				return nil, errors.Trace(err)
+			}
+			var canceledOrders []int64
+			for _, order := range cancelingOrders {
+				canceledOrders = append(canceledOrders, order.OrderID)
+			}
+			return canceledOrders, nil
+		})
+}
+
+func futuresListOrders(c *cli.Context) error {
+	symbol := c.String("symbol")
+	all := c.Bool("all")
+	limit := c.Int("limit")
+	if all && symbol == "" {
+		return errors.New("symbol is required")
+	}
+	return accountsDo(func(account *Account) (interface{}, error) {
+		if all {
+			orders, err := account.ListFuturesOrders(symbol, limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return orders, nil
+		}
+		orders, err := account.ListFuturesOpenOrders(symbol)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return orders, nil
+	})
+}
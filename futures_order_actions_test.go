@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFuturesOrder(t *testing.T) {
+	tests := []struct {
+		order FuturesOrder
+		valid bool
+	}{
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", PositionSide: "BOTH", Quantity: "1", Price: "100"}, true},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", PositionSide: "BOTH", Quantity: "1"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", PositionSide: "BOTH", Quantity: "1", Price: "100"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP", PositionSide: "BOTH", Quantity: "1", Price: "100"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", PositionSide: "BOTH", StopPrice: "90", ClosePosition: true}, true},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", PositionSide: "BOTH", StopPrice: "90", Quantity: "1", ClosePosition: true}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "TRAILING_STOP_MARKET", PositionSide: "BOTH", Quantity: "1"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "TRAILING_STOP_MARKET", PositionSide: "BOTH", Quantity: "1", CallbackRate: "1"}, true},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", PositionSide: "LONG", Quantity: "1", ReduceOnly: true}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", PositionSide: "LONG", Quantity: "1"}, true},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "HOLD", Type: "MARKET", PositionSide: "BOTH", Quantity: "1"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", PositionSide: "BOTH", Quantity: "20%"}, true},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", PositionSide: "BOTH", Quantity: "abc%"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", PositionSide: "BOTH", Quantity: "1,5"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", PositionSide: "BOTH", Quantity: "0"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", PositionSide: "BOTH", Quantity: "1", Price: "1e"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", PositionSide: "BOTH", Quantity: "1", StopPrice: "-90"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "TRAILING_STOP_MARKET", PositionSide: "BOTH", Quantity: "1", CallbackRate: "x"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "TRAILING_STOP_MARKET", PositionSide: "BOTH", Quantity: "1", CallbackRate: "1", ActivationPrice: "abc"}, false},
+		{FuturesOrder{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", PositionSide: "BOTH", StopPrice: "110", ClosePosition: true, ReduceOnly: true}, false},
+	}
+	for _, test := range tests {
+		err := ValidateFuturesOrder(&test.order)
+		assert.Equal(t, test.valid, err == nil, "%+v: %v", test.order, err)
+	}
+}
+
+func TestCloseOnTrigger(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(closeOnTrigger(&FuturesOrder{Type: "STOP_MARKET", ClosePosition: true}))
+	assert.True(closeOnTrigger(&FuturesOrder{Type: "TAKE_PROFIT_MARKET", ClosePosition: true}))
+	assert.False(closeOnTrigger(&FuturesOrder{Type: "STOP_MARKET"}))
+	assert.False(closeOnTrigger(&FuturesOrder{Type: "MARKET", ClosePosition: true}))
+	assert.False(closeOnTrigger(&FuturesOrder{Type: "TRAILING_STOP_MARKET", ClosePosition: true}))
+}
+
+func TestFuturesPercentQuantity(t *testing.T) {
+	assert := assert.New(t)
+	q := FuturesPercentQuantity(0.5, decimal.NewFromInt(1000), decimal.NewFromInt(10), decimal.NewFromInt(20000))
+	assert.Equal("0.25", q.String())
+	assert.True(FuturesPercentQuantity(0.5, decimal.NewFromInt(1000), decimal.NewFromInt(10), decimal.Decimal{}).IsZero())
+}
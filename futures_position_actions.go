@@ -0,0 +1,357 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// position modes of USDT-M futures account
+const (
+	positionModeHedge  = "hedge"
+	positionModeOneway = "oneway"
+)
+
+// action types of updating position margin
+const (
+	positionMarginAdd    = 1
+	positionMarginReduce = 2
+)
+
+// FuturesSetting define a change of leverage, margin type, position mode or
+// position margin of account
+type FuturesSetting struct {
+	Symbol       string `json:"symbol,omitempty"`
+	PositionSide string `json:"position_side,omitempty"`
+	Current      string `json:"current"`
+	Target       string `json:"target"`
+	// Amount is the margin added to or reduced from position
+	Amount string `json:"amount,omitempty"`
+	// PositionNotional and MaxNotional are checked when changing leverage
+	PositionNotional string `json:"position_notional,omitempty"`
+	MaxNotional      string `json:"max_notional,omitempty"`
+	// Changed is false when current setting is already the target
+	Changed bool `json:"changed"`
+}
+
+// MaxNotionalOfLeverage return max position notional allowed at leverage by
+// leverage brackets of symbol
+func MaxNotionalOfLeverage(brackets []futures.Bracket, leverage int) (decimal.Decimal, error) {
+	if leverage < 1 {
+		return decimal.Decimal{}, errors.Errorf("invalid leverage %d", leverage)
+	}
+	maxNotional := decimal.Decimal{}
+	maxLeverage := 0
+	for _, bracket := range brackets {
+		if bracket.InitialLeverage > maxLeverage {
+			maxLeverage = bracket.InitialLeverage
+		}
+		if bracket.InitialLeverage >= leverage {
+			maxNotional = decimal.Max(maxNotional, decimal.NewFromFloat(bracket.NotionalCap))
+		}
+	}
+	if leverage > maxLeverage {
+		return decimal.Decimal{}, errors.Errorf("leverage %d is greater than max leverage %d", leverage, maxLeverage)
+	}
+	return maxNotional, nil
+}
+
+// symbolPositions return positions of symbol, or all positions if symbol is
+// empty
+func (account *Account) symbolPositions(symbol string) ([]*futures.PositionRisk, error) {
+	positions, err := account.ListFuturesPositions()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var l []*futures.PositionRisk
+	for _, position := range positions {
+		if symbol == "" || position.Symbol == symbol {
+			l = append(l, position)
+		}
+	}
+	if symbol != "" && len(l) == 0 {
+		return nil, errors.Errorf("position %s not found", symbol)
+	}
+	return l, nil
+}
+
+// checkNoFuturesExposure return error if there is open position or open order
+// of symbol, or of any symbol if symbol is empty
+func (account *Account) checkNoFuturesExposure(positions []*futures.PositionRisk, symbol string) error {
+	for _, position := range positions {
+		if !decimal.RequireFromString(position.PositionAmt).IsZero() {
+			return errors.Errorf("position %s %s is open", position.Symbol, position.PositionSide)
+		}
+	}
+	orders, err := account.ListFuturesOpenOrders(symbol)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(orders) > 0 {
+		return errors.Errorf("%d futures open orders exist", len(orders))
+	}
+	return nil
+}
+
+// planFuturesLeverage check leverage against brackets and notional of
+// current position of symbol
+func (account *Account) planFuturesLeverage(symbol string, leverage int) (*FuturesSetting, error) {
+	brackets, err := account.ListFuturesLeverageBrackets(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	maxNotional, err := MaxNotionalOfLeverage(brackets, leverage)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	positions, err := account.symbolPositions(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	notional := decimal.Decimal{}
+	for _, position := range positions {
+		amount := decimal.RequireFromString(position.PositionAmt).Abs()
+		notional = notional.Add(amount.Mul(decimal.RequireFromString(position.MarkPrice)))
+	}
+	if notional.GreaterThan(maxNotional) {
+		return nil, errors.Errorf("position notional %s is greater than max notional %s of leverage %d",
+			notional, maxNotional, leverage)
+	}
+	target := strconv.Itoa(leverage)
+	return &FuturesSetting{
+		Symbol:           symbol,
+		Current:          positions[0].Leverage,
+		Target:           target,
+		PositionNotional: notional.String(),
+		MaxNotional:      maxNotional.String(),
+		Changed:          positions[0].Leverage != target,
+	}, nil
+}
+
+// planFuturesMarginType check margin type of symbol can be changed, which
+// requires no open position and order of symbol
+func (account *Account) planFuturesMarginType(symbol, marginType string) (*FuturesSetting, error) {
+	positions, err := account.symbolPositions(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// margin type of position risk is cross or isolated
+	current := string(futures.MarginTypeCrossed)
+	if positions[0].MarginType == "isolated" {
+		current = string(futures.MarginTypeIsolated)
+	}
+	s := &FuturesSetting{Symbol: symbol, Current: current, Target: marginType, Changed: current != marginType}
+	if s.Changed {
+		if err := account.checkNoFuturesExposure(positions, symbol); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return s, nil
+}
+
+// planFuturesPositionMode check position mode can be changed, which requires
+// no open position and order of any symbol
+func (account *Account) planFuturesPositionMode(mode string) (*FuturesSetting, error) {
+	dualSide, err := account.GetFuturesPositionMode()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	current := positionModeOneway
+	if dualSide {
+		current = positionModeHedge
+	}
+	s := &FuturesSetting{Current: current, Target: mode, Changed: current != mode}
+	if s.Changed {
+		positions, err := account.symbolPositions("")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := account.checkNoFuturesExposure(positions, ""); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return s, nil
+}
+
+// planFuturesPositionMargin check position is isolated and open, and margin
+// reduced is not greater than isolated margin
+func (account *Account) planFuturesPositionMargin(symbol, positionSide, amount string, actionType int) (*FuturesSetting, error) {
+	position, err := account.futuresPosition(symbol, positionSide)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if position.MarginType != "isolated" {
+		return nil, errors.Errorf("position %s %s is not isolated", symbol, positionSide)
+	}
+	if decimal.RequireFromString(position.PositionAmt).IsZero() {
+		return nil, errors.Errorf("position %s %s is not open", symbol, positionSide)
+	}
+	current := decimal.RequireFromString(position.IsolatedMargin)
+	delta := decimal.RequireFromString(amount)
+	if actionType == positionMarginReduce {
+		if delta.GreaterThan(current) {
+			return nil, errors.Errorf("amount %s is greater than isolated margin %s", amount, current)
+		}
+		delta = delta.Neg()
+	}
+	return &FuturesSetting{
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Current:      current.String(),
+		Target:       current.Add(delta).String(),
+		Amount:       amount,
+		Changed:      true,
+	}, nil
+}
+
+// changeFuturesSettings compute settings of accounts, then change those not
+// at target yet after confirmation
+func changeFuturesSettings(c *cli.Context, config *Config, description string,
+	compute func(*Account) (*FuturesSetting, error),
+	send func(*Account, *FuturesSetting) error) error {
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			s, err := compute(account)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return s, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	numAccounts := 0
+	for _, res := range results {
+		if s, ok := res.(*FuturesSetting); ok && s.Changed {
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numAccounts > 0 {
+		if err := confirm("%s on %d accounts?", description, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			s, ok := results[account.Name].(*FuturesSetting)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			if !s.Changed {
+				return s, nil
+			}
+			if err := send(account, s); err != nil {
+				return nil, errors.Trace(err)
+			}
+			return s, nil
+		})
+}
+
+func futuresLeverage(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	leverage := c.Int("leverage")
+	if symbol == "" || leverage == 0 {
+		return errors.New("symbol and leverage are required")
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	return changeFuturesSettings(c, config, "change leverage of "+symbol+" to "+strconv.Itoa(leverage),
+		func(account *Account) (*FuturesSetting, error) {
+			return account.planFuturesLeverage(symbol, leverage)
+		},
+		func(account *Account, s *FuturesSetting) error {
+			_, err := account.ChangeFuturesLeverage(symbol, leverage)
+			return errors.Trace(err)
+		})
+}
+
+func futuresMarginType(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	marginType := strings.ToUpper(c.Args().First())
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if marginType != string(futures.MarginTypeIsolated) && marginType != string(futures.MarginTypeCrossed) {
+		return errors.Errorf("invalid margin type %q, must be ISOLATED or CROSSED", marginType)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	return changeFuturesSettings(c, config, "change margin type of "+symbol+" to "+marginType,
+		func(account *Account) (*FuturesSetting, error) {
+			return account.planFuturesMarginType(symbol, marginType)
+		},
+		func(account *Account, s *FuturesSetting) error {
+			return errors.Trace(account.ChangeFuturesMarginType(symbol, futures.MarginType(marginType)))
+		})
+}
+
+func futuresPositionMode(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	mode := strings.ToLower(c.Args().First())
+	if mode != positionModeHedge && mode != positionModeOneway {
+		return errors.Errorf("invalid position mode %q, must be hedge or oneway", mode)
+	}
+	return changeFuturesSettings(c, config, "change position mode to "+mode,
+		func(account *Account) (*FuturesSetting, error) {
+			return account.planFuturesPositionMode(mode)
+		},
+		func(account *Account, s *FuturesSetting) error {
+			return errors.Trace(account.ChangeFuturesPositionMode(mode == positionModeHedge))
+		})
+}
+
+func futuresPositionMargin(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	action := strings.ToLower(c.Args().First())
+	symbol := c.String("symbol")
+	positionSide := strings.ToUpper(c.String("position-side"))
+	amount := c.String("amount")
+	var actionType int
+	switch action {
+	case "add":
+		actionType = positionMarginAdd
+	case "reduce":
+		actionType = positionMarginReduce
+	default:
+		return errors.Errorf("invalid action %q, must be add or reduce", action)
+	}
+	if symbol == "" || amount == "" {
+		return errors.New("symbol and amount are required")
+	}
+	if v, err := decimal.NewFromString(amount); err != nil || !v.IsPositive() {
+		return errors.Errorf("invalid amount %s", amount)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	return changeFuturesSettings(c, config, action+" "+amount+" margin of position "+symbol+" "+positionSide,
+		func(account *Account) (*FuturesSetting, error) {
+			return account.planFuturesPositionMargin(symbol, positionSide, amount, actionType)
+		},
+		func(account *Account, s *FuturesSetting) error {
+			return errors.Trace(account.UpdateFuturesPositionMargin(symbol, positionSide, amount, actionType))
+		})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxNotionalOfLeverage(t *testing.T) {
+	brackets := []futures.Bracket{
+		{Bracket: 1, InitialLeverage: 125, NotionalFloor: 0, NotionalCap: 50000},
+		{Bracket: 2, InitialLeverage: 100, NotionalFloor: 50000, NotionalCap: 250000},
+		{Bracket: 3, InitialLeverage: 50, NotionalFloor: 250000, NotionalCap: 1000000},
+	}
+	tests := []struct {
+		leverage int
+		expected string
+		valid    bool
+	}{
+		{125, "50000", true},
+		{100, "250000", true},
+		{75, "250000", true},
+		{5, "1000000", true},
+		{150, "", false},
+		{0, "", false},
+	}
+	for _, test := range tests {
+		maxNotional, err := MaxNotionalOfLeverage(brackets, test.leverage)
+		if !test.valid {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, maxNotional.String())
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// GridLevel define a price level of grid and its open order
+type GridLevel struct {
+	Price         string `json:"price"`
+	Side          string `json:"side,omitempty"`
+	OrderID       int64  `json:"order_id,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	// Paired is set when the order is placed as counterpart of a fill
+	Paired bool `json:"paired,omitempty"`
+}
+
+// GridFill define a filled order of grid
+type GridFill struct {
+	Time     int64  `json:"time"`
+	Level    int    `json:"level"`
+	Side     string `json:"side"`
+	Price    string `json:"price"`
+	Quantity string `json:"quantity"`
+	OrderID  int64  `json:"order_id"`
+}
+
+// GridState define persisted state of grid of an account
+type GridState struct {
+	Symbol   string       `json:"symbol"`
+	Quantity string       `json:"quantity"`
+	Levels   []*GridLevel `json:"levels"`
+	Fills    []*GridFill  `json:"fills"`
+	Profit   string       `json:"profit"`
+	// Unpaired are fills whose counter level was occupied by another order
+	Unpaired []*GridFill `json:"unpaired,omitempty"`
+	// Seq is increased by every placed order to keep client order ids unique
+	Seq int `json:"seq"`
+}
+
+// newGridLevels return grids+1 levels from lower to upper, BUY orders are
+// placed below current price and SELL orders above it, the level closest to
+// current price is left empty
+func newGridLevels(lower, upper string, grids int, current decimal.Decimal, tickSize string) ([]*GridLevel, error) {
+	ladderPrices, err := LadderPrices(lower, upper, grids+1)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	prices := make([]decimal.Decimal, len(ladderPrices))
+	rounded, err := RoundLadderPrices(ladderPrices, tickSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for i, p := range rounded {
+		prices[i] = decimal.RequireFromString(p)
+	}
+	closest := 0
+	for i, p := range prices {
+		if p.Sub(current).Abs().LessThan(prices[closest].Sub(current).Abs()) {
+			closest = i
+		}
+	}
+	levels := make([]*GridLevel, len(prices))
+	for i := range prices {
+		levels[i] = &GridLevel{Price: rounded[i]}
+		if i < closest {
+			levels[i].Side = "BUY"
+		} else if i > closest {
+			levels[i].Side = "SELL"
+		}
+	}
+	return levels, nil
+}
+
+// counterLevel return level and side of the order paired with a fill at
+// level i, a filled BUY is paired with a SELL one level up and vice versa
+func counterLevel(i int, side string, numLevels int) (int, string, bool) {
+	if side == "BUY" {
+		if i+1 >= numLevels {
+			return 0, "", false
+		}
+		return i + 1, "SELL", true
+	}
+	if i-1 < 0 {
+		return 0, "", false
+	}
+	return i - 1, "BUY", true
+}
+
+// coverSellLevels keep SELL side of the lowest levels covered by held base
+// asset, SELL levels above them are left empty until BUY orders below are
+// filled and paired with them. It returns number of SELL levels kept
+func coverSellLevels(levels []*GridLevel, quantity, held decimal.Decimal) int {
+	var n int
+	for _, level := range levels {
+		if level.Side != "SELL" {
+			continue
+		}
+		if held.LessThan(quantity) {
+			level.Side = ""
+			continue
+		}
+		held = held.Sub(quantity)
+		n++
+	}
+	return n
+}
+
+func loadGridStates(filePath string) (map[string]*GridState, error) {
+	states := make(map[string]*GridState)
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return states, nil
+}
+
+func saveGridStates(filePath string, states map[string]*GridState) error {
+	return errors.Trace(writeJSONFile(filePath, states))
+}
+
+// newGridState compute levels and quantity per level of grid, investment is
+// in quote asset and evenly split between grids. SELL levels are sized from
+// held base asset, no base asset is bought to start the grid
+func (account *Account) newGridState(symbol, lower, upper string, grids int, investment string,
+	accountBalances map[string]map[string]binance.Balance) (*GridState, error) {
+	err := account.loadSymbols()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", symbol)
+	}
+	priceFilter := info.PriceFilter()
+	if priceFilter == nil {
+		return nil, errors.Errorf("price filter of %s not found", symbol)
+	}
+	lotSize := info.LotSizeFilter()
+	if lotSize == nil {
+		return nil, errors.Errorf("lot size filter of %s not found", symbol)
+	}
+	marketPrice, err := account.marketPrice(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	current := decimal.RequireFromString(marketPrice)
+	invest, err := decimal.NewFromString(investment)
+	if err != nil || !invest.IsPositive() {
+		return nil, errors.Errorf("invalid investment %s", investment)
+	}
+	amount := invest.
+		Div(decimal.NewFromInt(int64(grids))).
+		DivRound(current, int32(info.BaseAssetPrecision))
+	quantity := AmountToLotSize(amount.String(), lotSize.MinQuantity, lotSize.StepSize, info.BaseAssetPrecision)
+	if !decimal.RequireFromString(quantity).IsPositive() {
+		return nil, errors.Errorf("investment %s is too small for %d grids", investment, grids)
+	}
+	levels, err := newGridLevels(lower, upper, grids, current, priceFilter.TickSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	held, err := account.freeBalance(info.BaseAsset, accountBalances)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	coverSellLevels(levels, decimal.RequireFromString(quantity), held)
+	return &GridState{
+		Symbol:   symbol,
+		Quantity: quantity,
+		Levels:   levels,
+		Profit:   "0",
+	}, nil
+}
+
+// placeGridOrder create order of level, the client order id is saved before
+// creating so an order of unknown state is found again after restart
+func (account *Account) placeGridOrder(state *GridState, i int, save func() error) error {
+	level := state.Levels[i]
+	if level.ClientOrderID == "" {
+		state.Seq++
+		level.ClientOrderID = ClientOrderID("grid", account.Name, state.Symbol, level.Price,
+			level.Side, strconv.Itoa(state.Seq))
+		if err := save(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	res, err := account.createPlannedOrder(&OrderPlan{
+		Symbol:        state.Symbol,
+		Side:          level.Side,
+		Quantity:      state.Quantity,
+		Price:         level.Price,
+		ClientOrderID: level.ClientOrderID,
+	}, false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	level.OrderID = res.(int64)
+	return errors.Trace(save())
+}
+
+// syncGrid reconcile levels against open orders, pair filled orders in
+// order of fill time and replace orders which are canceled outside of the grid
+func (account *Account) syncGrid(state *GridState, save func() error) error {
+	orders, err := account.ListOpenOrders(state.Symbol)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	openOrders := make(map[int64]bool)
+	for _, order := range orders {
+		openOrders[order.OrderID] = true
+	}
+	var fills []*GridFill
+	for i, level := range state.Levels {
+		if level.Side == "" || level.OrderID == 0 || openOrders[level.OrderID] {
+			continue
+		}
+		order, err := account.GetOrder(state.Symbol, level.OrderID, "")
+		if err != nil {
+			return errors.Trace(err)
+		}
+		switch order.Status {
+		case binance.OrderStatusTypeFilled:
+			fills = append(fills, &GridFill{
+				Time:     order.UpdateTime,
+				Level:    i,
+				Side:     level.Side,
+				Price:    level.Price,
+				Quantity: order.ExecutedQuantity,
+				OrderID:  order.OrderID,
+			})
+		case binance.OrderStatusTypeCanceled, binance.OrderStatusTypeExpired, binance.OrderStatusTypeRejected:
+			log.Printf("%s: grid order %d at %s is %s, placing again", account.Name, order.OrderID, level.Price, order.Status)
+			level.OrderID = 0
+			level.ClientOrderID = ""
+		}
+	}
+	sort.Slice(fills, func(i, j int) bool {
+		return fills[i].Time < fills[j].Time
+	})
+	for _, fill := range fills {
+		if !applyGridFill(state, fill) {
+			log.Printf("%s: counter level of grid %s fill at %s is occupied, fill is left unpaired",
+				account.Name, fill.Side, fill.Price)
+		}
+		log.Printf("%s: grid %s order %d filled at %s, profit %s", account.Name, fill.Side, fill.OrderID, fill.Price, state.Profit)
+	}
+	if len(fills) > 0 {
+		if err := save(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for i, level := range state.Levels {
+		if level.Side != "" && level.OrderID == 0 {
+			if err := account.placeGridOrder(state, i, save); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyGridFill record fill and move its order to the counter level, a fill
+// whose counter level already holds an order is kept in Unpaired instead of
+// replacing that order, return false if the fill is not paired. A fill at the
+// edge of grid without counter level places the same order again
+func applyGridFill(state *GridState, fill *GridFill) bool {
+	i := fill.Level
+	level := state.Levels[i]
+	state.Fills = append(state.Fills, fill)
+	// a paired fill closes a cycle with the fill of the adjacent level
+	if level.Paired {
+		var gain decimal.Decimal
+		price := decimal.RequireFromString(level.Price)
+		quantity := decimal.RequireFromString(fill.Quantity)
+		if level.Side == "SELL" && i > 0 {
+			gain = price.Sub(decimal.RequireFromString(state.Levels[i-1].Price)).Mul(quantity)
+		} else if level.Side == "BUY" && i+1 < len(state.Levels) {
+			gain = decimal.RequireFromString(state.Levels[i+1].Price).Sub(price).Mul(quantity)
+		}
+		state.Profit = decimal.RequireFromString(state.Profit).Add(gain).String()
+	}
+	*level = GridLevel{Price: level.Price}
+	j, side, ok := counterLevel(i, fill.Side, len(state.Levels))
+	if !ok {
+		level.Side = fill.Side
+		return true
+	}
+	if state.Levels[j].Side != "" {
+		state.Unpaired = append(state.Unpaired, fill)
+		return false
+	}
+	state.Levels[j] = &GridLevel{Price: state.Levels[j].Price, Side: side, Paired: true}
+	return true
+}
+
+func runGrid(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+	symbol := c.String("symbol")
+	lower := c.String("lower")
+	upper := c.String("upper")
+	grids := c.Int("grids")
+	investment := c.String("investment")
+	interval := c.Duration("interval")
+	statePath := c.String("state")
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if statePath == "" {
+		statePath = "grid-" + symbol + ".json"
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	states, err := loadGridStates(statePath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	save := func() error {
+		return saveGridStates(statePath, states)
+	}
+
+	accounts := findAccounts(name)
+	if err := config.CheckAccounts(len(accounts)); err != nil {
+		return errors.Trace(err)
+	}
+	var numNew int
+	total := decimal.Decimal{}
+	for _, account := range accounts {
+		if _, ok := states[account.Name]; ok {
+			continue
+		}
+		if lower == "" || upper == "" || grids < 1 || investment == "" {
+			return errors.New("lower, upper, grids and investment are required to start a grid")
+		}
+		state, err := account.newGridState(symbol, lower, upper, grids, investment, accountBalances)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		notional := decimal.RequireFromString(state.Quantity).Mul(decimal.RequireFromString(upper))
+		if err := config.CheckNotional(account.Name, notional); err != nil {
+			return errors.Trace(err)
+		}
+		states[account.Name] = state
+		total = total.Add(notional.Mul(decimal.NewFromInt(int64(len(state.Levels)))))
+		numNew++
+	}
+	// resuming grids only places orders already confirmed when they started
+	if numNew > 0 {
+		if err := confirm("run grid of %s on %d accounts, starting %d new grids with total notional up to %s?",
+			symbol, len(accounts), numNew, total); err != nil {
+			return errors.Trace(err)
+		}
+		if err := save(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	stopC := make(chan os.Signal, 1)
+	signal.Notify(stopC, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, account := range accounts {
+			state, ok := states[account.Name]
+			if !ok || state.Symbol != symbol {
+				continue
+			}
+			if err := account.syncGrid(state, save); err != nil {
+				log.Printf("%s: failed to sync grid: %s", account.Name, err)
+			}
+		}
+		select {
+		case <-stopC:
+			return print(states)
+		case <-ticker.C:
+		}
+	}
+}
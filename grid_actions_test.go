@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGridLevels(t *testing.T) {
+	assert := assert.New(t)
+	levels, err := newGridLevels("20", "24", 4, decimal.RequireFromString("22.3"), "0.01")
+	assert.NoError(err)
+	var prices, sides []string
+	for _, level := range levels {
+		prices = append(prices, level.Price)
+		sides = append(sides, level.Side)
+	}
+	assert.Equal([]string{"20", "21", "22", "23", "24"}, prices)
+	assert.Equal([]string{"BUY", "BUY", "", "SELL", "SELL"}, sides)
+
+	_, err = newGridLevels("20", "abc", 4, decimal.RequireFromString("22.3"), "0.01")
+	assert.Error(err)
+}
+
+func TestCoverSellLevels(t *testing.T) {
+	assert := assert.New(t)
+	sides := func(levels []*GridLevel) []string {
+		var l []string
+		for _, level := range levels {
+			l = append(l, level.Side)
+		}
+		return l
+	}
+	levels, err := newGridLevels("20", "24", 4, decimal.RequireFromString("20.3"), "0.01")
+	assert.NoError(err)
+	assert.Equal(2, coverSellLevels(levels, decimal.NewFromInt(1), decimal.RequireFromString("2.5")))
+	assert.Equal([]string{"", "SELL", "SELL", "", ""}, sides(levels))
+
+	levels, err = newGridLevels("20", "24", 4, decimal.RequireFromString("22.3"), "0.01")
+	assert.NoError(err)
+	assert.Equal(0, coverSellLevels(levels, decimal.NewFromInt(1), decimal.Decimal{}))
+	assert.Equal([]string{"BUY", "BUY", "", "", ""}, sides(levels))
+}
+
+func TestCounterLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	j, side, ok := counterLevel(1, "BUY", 5)
+	assert.True(ok)
+	assert.Equal(2, j)
+	assert.Equal("SELL", side)
+
+	j, side, ok = counterLevel(3, "SELL", 5)
+	assert.True(ok)
+	assert.Equal(2, j)
+	assert.Equal("BUY", side)
+
+	_, _, ok = counterLevel(4, "BUY", 5)
+	assert.False(ok)
+	_, _, ok = counterLevel(0, "SELL", 5)
+	assert.False(ok)
+}
+
+func TestApplyGridFill(t *testing.T) {
+	assert := assert.New(t)
+	levels, err := newGridLevels("20", "24", 4, decimal.RequireFromString("22.3"), "0.01")
+	assert.NoError(err)
+	state := &GridState{Quantity: "1", Profit: "0", Levels: levels}
+	for i, level := range state.Levels {
+		if level.Side != "" {
+			level.OrderID = int64(i + 1)
+		}
+	}
+	sides := func() []string {
+		var l []string
+		for _, level := range state.Levels {
+			l = append(l, level.Side)
+		}
+		return l
+	}
+
+	// both BUY orders fill in one interval, the later one pairs with the
+	// level freed by the earlier one
+	assert.True(applyGridFill(state, &GridFill{Time: 1, Level: 1, Side: "BUY", Price: "21", Quantity: "1"}))
+	assert.Equal([]string{"BUY", "", "SELL", "SELL", "SELL"}, sides())
+	assert.Equal(int64(0), state.Levels[2].OrderID)
+	assert.Equal(int64(4), state.Levels[3].OrderID)
+
+	// SELL at 23 pairs with BUY at 22 which is occupied by the SELL placed above
+	assert.False(applyGridFill(state, &GridFill{Time: 2, Level: 3, Side: "SELL", Price: "23", Quantity: "1"}))
+	assert.Len(state.Unpaired, 1)
+	assert.Equal([]string{"BUY", "", "SELL", "", "SELL"}, sides())
+
+	// cycle started by a SELL at 24 and closed by a BUY at 23 is profitable
+	assert.True(applyGridFill(state, &GridFill{Time: 3, Level: 4, Side: "SELL", Price: "24", Quantity: "1"}))
+	assert.Equal("BUY", state.Levels[3].Side)
+	assert.True(applyGridFill(state, &GridFill{Time: 4, Level: 3, Side: "BUY", Price: "23", Quantity: "1"}))
+	assert.Equal("1", state.Profit)
+
+	// SELL at 22 paired with BUY at 21 is profitable too
+	assert.True(applyGridFill(state, &GridFill{Time: 5, Level: 2, Side: "SELL", Price: "22", Quantity: "1"}))
+	assert.Equal("2", state.Profit)
+	assert.Len(state.Fills, 5)
+
+	// BUY at the lowest level has a counter level above it, but SELL there
+	// has none and is placed again instead of leaving the level empty
+	assert.True(applyGridFill(state, &GridFill{Time: 6, Level: 0, Side: "SELL", Price: "20", Quantity: "1"}))
+	assert.Equal("SELL", state.Levels[0].Side)
+	assert.False(state.Levels[0].Paired)
+}
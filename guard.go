@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// CheckSymbol check if symbol is allowed by limits
+func (c *Config) CheckSymbol(symbol string) error {
+	if StrContains(c.Limits.DenySymbols, symbol) {
+		return errors.Errorf("symbol %s is denied", symbol)
+	}
+	if len(c.Limits.AllowSymbols) > 0 && !StrContains(c.Limits.AllowSymbols, symbol) {
+		return errors.Errorf("symbol %s is not allowed", symbol)
+	}
+	return nil
+}
+
+// CheckNotional check order notional against global and account limits
+func (c *Config) CheckNotional(accountName string, notional decimal.Decimal) error {
+	if c.Limits.MaxOrderNotional != "" {
+		max := decimal.RequireFromString(c.Limits.MaxOrderNotional)
+		if notional.GreaterThan(max) {
+			return errors.Errorf("notional %s of account %s exceeds max order notional %s", notional, accountName, max)
+		}
+	}
+	for _, info := range c.Accounts {
+		if info.Name != accountName || info.MaxOrderNotional == "" {
+			continue
+		}
+		max := decimal.RequireFromString(info.MaxOrderNotional)
+		if notional.GreaterThan(max) {
+			return errors.Errorf("notional %s of account %s exceeds account max order notional %s", notional, accountName, max)
+		}
+	}
+	return nil
+}
+
+// CheckAccounts check number of accounts touched by a command
+func (c *Config) CheckAccounts(n int) error {
+	if c.Limits.MaxAccounts > 0 && n > c.Limits.MaxAccounts {
+		return errors.Errorf("%d accounts exceeds max accounts %d", n, c.Limits.MaxAccounts)
+	}
+	return nil
+}
+
+// confirm ask user to confirm a destructive command unless --yes is set
+func confirm(format string, args ...interface{}) error {
+	if yes {
+		return nil
+	}
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return askConfirm(os.Stdin, os.Stderr, fi.Mode()&os.ModeCharDevice != 0, fmt.Sprintf(format, args...))
+}
+
+// askConfirm print prompt to w and read answer from r, only y or yes
+// confirms. Input which is not a terminal is refused
+func askConfirm(r io.Reader, w io.Writer, isTerminal bool, prompt string) error {
+	if !isTerminal {
+		return errors.New("stdin is not a terminal, use --yes to confirm")
+	}
+	fmt.Fprintf(w, "%s [y/N] ", prompt)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.Trace(err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return errors.New("aborted")
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSymbol(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		limits Limits
+		symbol string
+		err    bool
+	}{
+		{
+			name:   "test with no limits",
+			symbol: "BNBUSDT",
+		},
+		{
+			name:   "test with allowed symbol",
+			limits: Limits{AllowSymbols: []string{"BNBUSDT", "BTCUSDT"}},
+			symbol: "BNBUSDT",
+		},
+		{
+			name:   "test with symbol not allowed",
+			limits: Limits{AllowSymbols: []string{"BTCUSDT"}},
+			symbol: "BNBUSDT",
+			err:    true,
+		},
+		{
+			name:   "test with denied symbol",
+			limits: Limits{DenySymbols: []string{"BNBUSDT"}},
+			symbol: "BNBUSDT",
+			err:    true,
+		},
+		{
+			name:   "test with symbol both allowed and denied",
+			limits: Limits{AllowSymbols: []string{"BNBUSDT"}, DenySymbols: []string{"BNBUSDT"}},
+			symbol: "BNBUSDT",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Limits: tt.limits}).CheckSymbol(tt.symbol)
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestCheckNotional(t *testing.T) {
+	assert := assert.New(t)
+	config := &Config{
+		Limits: Limits{MaxOrderNotional: "1000"},
+		Accounts: []AccountConfig{
+			{Name: "a1", MaxOrderNotional: "100"},
+			{Name: "a2"},
+		},
+	}
+	tests := []struct {
+		name     string
+		config   *Config
+		account  string
+		notional string
+		err      bool
+	}{
+		{
+			name:     "test with no limits",
+			config:   &Config{},
+			account:  "a1",
+			notional: "1000000",
+		},
+		{
+			name:     "test with notional under account limit",
+			config:   config,
+			account:  "a1",
+			notional: "100",
+		},
+		{
+			name:     "test with notional over account limit",
+			config:   config,
+			account:  "a1",
+			notional: "100.01",
+			err:      true,
+		},
+		{
+			name:     "test with notional under global limit",
+			config:   config,
+			account:  "a2",
+			notional: "1000",
+		},
+		{
+			name:     "test with notional over global limit",
+			config:   config,
+			account:  "a2",
+			notional: "1001",
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.CheckNotional(tt.account, decimal.RequireFromString(tt.notional))
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestCheckAccounts(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name        string
+		maxAccounts int
+		n           int
+		err         bool
+	}{
+		{
+			name: "test with no limit",
+			n:    100,
+		},
+		{
+			name:        "test with accounts at limit",
+			maxAccounts: 3,
+			n:           3,
+		},
+		{
+			name:        "test with accounts over limit",
+			maxAccounts: 3,
+			n:           4,
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Limits: Limits{MaxAccounts: tt.maxAccounts}}).CheckAccounts(tt.n)
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestAskConfirm(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name       string
+		input      string
+		isTerminal bool
+		err        bool
+	}{
+		{
+			name:       "test with y",
+			input:      "y\n",
+			isTerminal: true,
+		},
+		{
+			name:       "test with YES",
+			input:      " YES \n",
+			isTerminal: true,
+		},
+		{
+			name:       "test with yes without newline",
+			input:      "yes",
+			isTerminal: true,
+		},
+		{
+			name:       "test with n",
+			input:      "n\n",
+			isTerminal: true,
+			err:        true,
+		},
+		{
+			name:       "test with empty answer",
+			input:      "\n",
+			isTerminal: true,
+			err:        true,
+		},
+		{
+			name:  "test with non terminal input",
+			input: "y\n",
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := askConfirm(strings.NewReader(tt.input), &out, tt.isTerminal, "cancel 2 orders?")
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tt.isTerminal {
+				assert.Equal("cancel 2 orders? [y/N] ", out.String())
+			} else {
+				assert.Empty(out.String())
+			}
+		})
+	}
+}
+
+func TestConfirmWithYes(t *testing.T) {
+	origYes := yes
+	defer func() {
+		yes = origYes
+	}()
+	yes = true
+	assert.NoError(t, confirm("cancel %d orders?", 2))
+}
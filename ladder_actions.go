@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// ladderOrders compute orders of ladder from price from to price to, levels
+// under min notional are dropped
+func (account *Account) ladderOrders(symbol, side, from, to string, count int, quantity string,
+	weights []decimal.Decimal, tag string, accountBalances map[string]map[string]binance.Balance) ([]*OrderPlan, error) {
+	err := account.loadSymbols()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", symbol)
+	}
+	priceFilter := info.PriceFilter()
+	if priceFilter == nil {
+		return nil, errors.Errorf("price filter of %s not found", symbol)
+	}
+	lotSize := info.LotSizeFilter()
+	if lotSize == nil {
+		return nil, errors.Errorf("lot size filter of %s not found", symbol)
+	}
+	minNotional := decimal.Decimal{}
+	if f := info.MinNotionalFilter(); f != nil {
+		minNotional = decimal.RequireFromString(f.MinNotional)
+	}
+
+	// total is in base asset, except percent quantity of BUY side which
+	// is in quote asset and divided by price of each level
+	var total decimal.Decimal
+	isQuoteTotal := false
+	if strings.HasSuffix(quantity, "%") {
+		asset := info.BaseAsset
+		if side == "BUY" {
+			asset = info.QuoteAsset
+			isQuoteTotal = true
+		}
+		balance, err := account.freeBalance(asset, accountBalances)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		total = balance.Mul(decimal.NewFromFloat(StrToPct(quantity)))
+	} else {
+		total, err = decimal.NewFromString(quantity)
+		if err != nil || !total.IsPositive() {
+			return nil, errors.Errorf("invalid quantity %s", quantity)
+		}
+	}
+
+	ladderPrices, err := LadderPrices(from, to, count)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	prices, err := RoundLadderPrices(ladderPrices, priceFilter.TickSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var plans []*OrderPlan
+	for i, price := range prices {
+		priceDec := decimal.RequireFromString(price)
+		if !priceDec.IsPositive() {
+			continue
+		}
+		amount := total.Mul(weights[i])
+		if isQuoteTotal {
+			amount = amount.DivRound(priceDec, int32(info.BaseAssetPrecision))
+		}
+		levelQuantity := AmountToLotSize(amount.String(), lotSize.MinQuantity, lotSize.StepSize, info.BaseAssetPrecision)
+		notional := decimal.RequireFromString(levelQuantity).Mul(priceDec)
+		if notional.IsZero() || notional.LessThan(minNotional) {
+			continue
+		}
+		plans = append(plans, &OrderPlan{
+			Account:       account.Name,
+			Symbol:        symbol,
+			Side:          side,
+			Quantity:      levelQuantity,
+			Price:         price,
+			Notional:      notional.String(),
+			ClientOrderID: ClientOrderID(tag, account.Name, symbol, side, price),
+		})
+	}
+	return plans, nil
+}
+
+func createLadder(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+
+	symbol := c.String("symbol")
+	side := strings.ToUpper(c.String("side"))
+	from := c.String("from")
+	to := c.String("to")
+	count := c.Int("count")
+	quantity := c.String("quantity")
+	tag := c.String("tag")
+	isTest := c.Bool("test")
+	isDryRun := c.Bool("dry-run")
+	if symbol == "" || side == "" || from == "" || to == "" || quantity == "" {
+		return errors.New("symbol, side, from, to and quantity are required")
+	}
+	if side != "BUY" && side != "SELL" {
+		return errors.Errorf("invalid side %s", side)
+	}
+	if q, err := decimal.NewFromString(strings.TrimSuffix(quantity, "%")); err != nil || !q.IsPositive() {
+		return errors.Errorf("invalid quantity %s", quantity)
+	}
+	if err := CheckTag(tag); err != nil {
+		return errors.Trace(err)
+	}
+	if count < 1 {
+		return errors.New("count must be positive")
+	}
+	weights, err := LadderWeights(count, c.String("weight"), c.Float64("ratio"))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			plans, err := account.ladderOrders(symbol, side, from, to, count, quantity, weights, tag, accountBalances)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			for _, plan := range plans {
+				if err := config.CheckNotional(account.Name, decimal.RequireFromString(plan.Notional)); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+			return plans, nil
+		})
+	if isDryRun {
+		return print(results)
+	}
+	var numOrders, numAccounts int
+	total := decimal.Decimal{}
+	for _, res := range results {
+		if plans, ok := res.([]*OrderPlan); ok && len(plans) > 0 {
+			for _, plan := range plans {
+				total = total.Add(decimal.RequireFromString(plan.Notional))
+			}
+			numOrders += len(plans)
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if !isTest && numOrders > 0 {
+		if err := confirm("create %d orders on %d accounts with total notional %s?", numOrders, numAccounts, total); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			plans, ok := results[account.Name].([]*OrderPlan)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			orders := make(map[string]interface{})
+			for _, plan := range plans {
+				res, err := account.createPlannedOrder(plan, isTest)
+				if err != nil {
+					orders[plan.Price] = fmt.Sprintf("error: %s", err)
+					continue
+				}
+				orders[plan.Price] = res
+			}
+			return orders, nil
+		})
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"time"
 
 	"github.com/adshao/go-binance/v2"
 	"github.com/juju/errors"
@@ -16,6 +17,7 @@ var (
 	name     string
 	keyfile  string
 	debug    bool
+	yes      bool
 	accounts map[string]*Account
 	assets   []string
 )
@@ -54,11 +56,23 @@ func initAccounts() {
 			key.APIKey,
 			key.SecretKey,
 		)
+		futuresClient := binance.NewFuturesClient(
+			key.APIKey,
+			key.SecretKey,
+		)
+		deliveryClient := binance.NewDeliveryClient(
+			key.APIKey,
+			key.SecretKey,
+		)
 		if debug {
 			client.Debug = true
+			futuresClient.Debug = true
+			deliveryClient.Debug = true
 		}
 		account := new(Account)
 		account.Client = client
+		account.Futures = futuresClient
+		account.Delivery = deliveryClient
 		account.Name = key.Name
 		accounts[account.Name] = account
 	}
@@ -91,21 +105,49 @@ func runOnce(action func(*Account) (interface{}, error),
 	return accountsDo(action, postAction...)
 }
 
-func accountsDo(action func(*Account) (interface{}, error),
-	postAction ...func(map[string]interface{}) (interface{}, error)) error {
+// accountError is the result of account whose action failed, it is printed
+// as "error: ..." string
+type accountError struct {
+	err error
+}
+
+// Error return message of the error
+func (e *accountError) Error() string {
+	return e.err.Error()
+}
+
+// MarshalJSON marshal error as "error: ..." string
+func (e *accountError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("error: %s", e.err))
+}
+
+func accountsRun(action func(*Account) (interface{}, error)) map[string]interface{} {
 	accounts := findAccounts(name)
-	var ret interface{}
-	var err error
 	results := make(map[string]interface{})
 	for _, account := range accounts {
 		res, err := action(account)
 		if err != nil {
-			// return errors.Trace(err)
-			results[account.Name] = fmt.Sprintf("error: %s", err)
+			results[account.Name] = &accountError{err: err}
 		} else {
 			results[account.Name] = res
 		}
 	}
+	return results
+}
+
+// resultError return the error recorded by accountsRun for a failed account
+func resultError(res interface{}) error {
+	if e, ok := res.(*accountError); ok {
+		return e.err
+	}
+	return errors.Errorf("unexpected result %v", res)
+}
+
+func accountsDo(action func(*Account) (interface{}, error),
+	postAction ...func(map[string]interface{}) (interface{}, error)) error {
+	var ret interface{}
+	var err error
+	results := accountsRun(action)
 	if len(postAction) > 0 {
 		ret, err = postAction[0](results)
 		if err != nil {
@@ -150,6 +192,11 @@ func main() {
 			Name:  "configfile, f",
 			Usage: "config file",
 		},
+		cli.BoolFlag{
+			Name:        "yes, y",
+			Usage:       "skip confirmation of destructive commands",
+			Destination: &yes,
+		},
 	}
 	app.Commands = []cli.Command{
 		{
@@ -200,6 +247,10 @@ func main() {
 					Name:  "limit, l",
 					Usage: "limit num of trades",
 				},
+				cli.StringFlag{
+					Name:  "client-id-prefix",
+					Usage: "list orders with client order id prefix, e.g. the tag of create-order",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				return listOrders(c)
@@ -219,21 +270,69 @@ func main() {
 				},
 				cli.StringFlag{
 					Name:  "quantity",
-					Usage: "quantity of symbol: 20.120 or 50%",
+					Usage: "quantity of symbol: 20.120, 50% of free balance, 10%equity of total equity in quote asset or max of free balance less commission",
+				},
+				cli.StringFlag{
+					Name:  "quote-amount",
+					Usage: "amount of quote asset to spend or receive instead of quantity: 500",
+				},
+				cli.BoolFlag{
+					Name:  "split-even",
+					Usage: "split quantity between accounts so every account ends with equal holding of base asset",
 				},
 				cli.StringFlag{
 					Name:  "price",
-					Usage: "price of symbol",
+					Usage: "price of symbol, absolute or relative to bid, ask, mid, last or avg price: bid, ask-0.1%, mid+3ticks, last*0.98",
 				},
 				cli.BoolFlag{
 					Name:  "test",
 					Usage: "for test only, will not actually create order",
 				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "generate client order id from tag, account, symbol and side, open order with the id is not created again",
+				},
+				cli.BoolFlag{
+					Name:  "plan",
+					Usage: "print computed order of each account without creating it",
+				},
+				cli.StringFlag{
+					Name:  "from-file",
+					Usage: "create orders of csv or yaml file with columns account, symbol, side, type, quantity, price, tif and client_id",
+				},
+				cli.StringFlag{
+					Name:  "results",
+					Usage: "results file of --from-file, default to <file>.results.json",
+				},
+				cli.IntFlag{
+					Name:  "concurrency",
+					Usage: "max concurrent requests of --from-file",
+					Value: 4,
+				},
 			},
 			Action: func(c *cli.Context) error {
 				return createOrder(c)
 			},
 		},
+		{
+			Name:      "apply",
+			Usage:     "create orders saved by create-order --plan",
+			ArgsUsage: "<planfile>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "tolerance",
+					Usage: "max drift of balance and price since planned: 0.01 or 1%",
+					Value: "1%",
+				},
+				cli.BoolFlag{
+					Name:  "test",
+					Usage: "for test only, will not actually create order",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return applyPlan(c)
+			},
+		},
 		{
 			Name:  "cancel-order",
 			Usage: "cancel open orders",
@@ -244,13 +343,353 @@ func main() {
 				},
 				cli.Int64Flag{
 					Name:  "order-id, id",
-					Usage: "cancel open order with order id",
+					Usage: "cancel open order with order id of account set by --name, filters are not allowed with it",
+				},
+				cli.StringFlag{
+					Name:  "client-id-prefix",
+					Usage: "cancel open orders with client order id prefix",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "cancel open orders with side: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "cancel open orders with order type: LIMIT, LIMIT_MAKER ...",
+				},
+				cli.StringFlag{
+					Name:  "price-above",
+					Usage: "cancel open orders with price above",
+				},
+				cli.StringFlag{
+					Name:  "price-below",
+					Usage: "cancel open orders with price below",
+				},
+				cli.DurationFlag{
+					Name:  "older-than",
+					Usage: "cancel open orders created before duration: 30m, 2h ...",
+				},
+				cli.IntFlag{
+					Name:  "keep-best",
+					Usage: "keep N orders closest to mid price of each symbol",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "list matching orders without canceling",
 				},
 			},
 			Action: func(c *cli.Context) error {
 				return cancelOrders(c)
 			},
 		},
+		{
+			Name:  "create-ladder",
+			Usage: "create LIMIT orders distributed across a price range",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BNBUSDT",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "side type: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "price of first level",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "price of last level",
+				},
+				cli.IntFlag{
+					Name:  "count",
+					Usage: "num of levels",
+					Value: 10,
+				},
+				cli.StringFlag{
+					Name:  "quantity",
+					Usage: "total quantity of symbol: 20.120 or 30%",
+				},
+				cli.StringFlag{
+					Name:  "weight",
+					Usage: "quantity weighting of levels: uniform, linear or geometric",
+					Value: "uniform",
+				},
+				cli.Float64Flag{
+					Name:  "ratio",
+					Usage: "ratio between levels of geometric weighting",
+					Value: 1.2,
+				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "generate client order ids from tag",
+				},
+				cli.BoolFlag{
+					Name:  "test",
+					Usage: "for test only, will not actually create orders",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "list computed orders without creating them",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return createLadder(c)
+			},
+		},
+		{
+			Name:  "grid",
+			Usage: "grid trading bot",
+			Subcommands: []cli.Command{
+				{
+					Name:  "run",
+					Usage: "keep a grid of LIMIT orders alive, state is saved to file and resumed after restart",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "symbol, s",
+							Usage: "symbol name: BNBUSDT",
+						},
+						cli.StringFlag{
+							Name:  "lower",
+							Usage: "lowest price of grid",
+						},
+						cli.StringFlag{
+							Name:  "upper",
+							Usage: "highest price of grid",
+						},
+						cli.IntFlag{
+							Name:  "grids",
+							Usage: "num of grids between lower and upper price",
+						},
+						cli.StringFlag{
+							Name:  "investment",
+							Usage: "investment of each account in quote asset",
+						},
+						cli.StringFlag{
+							Name:  "state",
+							Usage: "state file path, default grid-<symbol>.json",
+						},
+						cli.DurationFlag{
+							Name:  "interval",
+							Usage: "interval to check orders",
+							Value: 10 * time.Second,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return runGrid(c)
+					},
+				},
+			},
+		},
+		{
+			Name:  "execute",
+			Usage: "execute large orders with algorithms",
+			Subcommands: []cli.Command{
+				{
+					Name:  "twap",
+					Usage: "split order into child orders evenly over time",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "symbol, s",
+							Usage: "symbol name: BNBUSDT",
+						},
+						cli.StringFlag{
+							Name:  "side",
+							Usage: "side type: SELL or BUY",
+						},
+						cli.StringFlag{
+							Name:  "quantity",
+							Usage: "quantity of symbol: 20.120 or 50%",
+						},
+						cli.DurationFlag{
+							Name:  "duration",
+							Usage: "total duration of execution",
+							Value: time.Hour,
+						},
+						cli.IntFlag{
+							Name:  "slices",
+							Usage: "num of child orders",
+							Value: 12,
+						},
+						cli.StringFlag{
+							Name:  "randomize",
+							Usage: "randomize time of child orders by ratio of interval: 0.2 or 20%",
+							Value: "0",
+						},
+						cli.StringFlag{
+							Name:  "type",
+							Usage: "child order type: limit resting at best bid/ask until next slice, or market",
+							Value: "limit",
+						},
+						cli.StringFlag{
+							Name:  "limit-price",
+							Usage: "worst price of child orders",
+						},
+						cli.StringFlag{
+							Name:  "tag",
+							Usage: "generate client order ids from tag",
+						},
+						cli.StringFlag{
+							Name:  "report",
+							Usage: "file path of execution report updated after each slice",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return executeTWAP(c)
+					},
+				},
+				{
+					Name:  "pov",
+					Usage: "size child orders to keep fills at a fraction of market volume",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "symbol, s",
+							Usage: "symbol name: BNBUSDT",
+						},
+						cli.StringFlag{
+							Name:  "side",
+							Usage: "side type: SELL or BUY",
+						},
+						cli.StringFlag{
+							Name:  "quantity",
+							Usage: "quantity of symbol: 20.120 or 50%",
+						},
+						cli.StringFlag{
+							Name:  "rate",
+							Usage: "target participation of market volume shared by accounts: 0.1 or 10%",
+							Value: "10%",
+						},
+						cli.StringFlag{
+							Name:  "min-child",
+							Usage: "min quantity of child orders",
+						},
+						cli.StringFlag{
+							Name:  "max-child",
+							Usage: "max quantity of child orders",
+						},
+						cli.DurationFlag{
+							Name:  "deadline",
+							Usage: "stop after duration even if quantity is not filled",
+							Value: time.Hour,
+						},
+						cli.DurationFlag{
+							Name:  "interval",
+							Usage: "interval between child orders",
+							Value: 10 * time.Second,
+						},
+						cli.StringFlag{
+							Name:  "type",
+							Usage: "child order type: limit at best bid/ask or market",
+							Value: "limit",
+						},
+						cli.StringFlag{
+							Name:  "limit-price",
+							Usage: "worst price of child orders",
+						},
+						cli.StringFlag{
+							Name:  "tag",
+							Usage: "generate client order ids from tag",
+						},
+						cli.StringFlag{
+							Name:  "report",
+							Usage: "file path of execution report updated after each child order",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return executePOV(c)
+					},
+				},
+			},
+		},
+		{
+			Name:  "trailing-stop",
+			Usage: "sell when price retraces from its best price by callback ratio",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BNBUSDT",
+				},
+				cli.StringFlag{
+					Name:  "quantity",
+					Usage: "quantity of symbol to sell: 20.120 or 50%",
+				},
+				cli.StringFlag{
+					Name:  "callback",
+					Usage: "retracement from best price to trigger: 0.02 or 2%",
+				},
+				cli.StringFlag{
+					Name:  "activation",
+					Usage: "start tracking when price reaches activation price",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "order type when triggered: market or limit below best bid",
+					Value: "market",
+				},
+				cli.StringFlag{
+					Name:  "limit-offset",
+					Usage: "offset of limit price below best bid: 0.005 or 0.5%",
+					Value: "0.5%",
+				},
+				cli.StringFlag{
+					Name:  "state",
+					Usage: "state file path, default trailing-<symbol>.json",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return trailingStop(c)
+			},
+		},
+		{
+			Name:  "amend-order",
+			Usage: "cancel and replace open LIMIT orders with new price or quantity",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "amend open orders with symbol",
+				},
+				cli.Int64Flag{
+					Name:  "order-id, id",
+					Usage: "amend open order with order id of account set by --name",
+				},
+				cli.StringFlag{
+					Name:  "price",
+					Usage: "new price: 21.5, +0.5%, -1% or +3ticks",
+				},
+				cli.StringFlag{
+					Name:  "quantity",
+					Usage: "new quantity: 10, +50% or -2ticks of lot step size",
+				},
+				cli.StringFlag{
+					Name:  "client-id-prefix",
+					Usage: "amend open orders with client order id prefix",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "amend open orders with side: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "price-above",
+					Usage: "amend open orders with price above",
+				},
+				cli.StringFlag{
+					Name:  "price-below",
+					Usage: "amend open orders with price below",
+				},
+				cli.DurationFlag{
+					Name:  "older-than",
+					Usage: "amend open orders created before duration: 30m, 2h ...",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "list replacements without amending",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return amendOrders(c)
+			},
+		},
 		{
 			Name:  "list-symbol",
 			Usage: "list symbols info",
@@ -303,6 +742,726 @@ func main() {
 				return listMarginBalances(c)
 			},
 		},
+		{
+			Name:  "margin-borrow",
+			Usage: "borrow asset in margin account",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: BTC",
+				},
+				cli.StringFlag{
+					Name:  "amount",
+					Usage: "amount to borrow: 0.1 or 50% of max borrowable",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print amount of each account without borrowing",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginBorrow(c)
+			},
+		},
+		{
+			Name:  "margin-repay",
+			Usage: "repay margin loan of asset",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: BTC",
+				},
+				cli.StringFlag{
+					Name:  "amount",
+					Usage: "amount to repay: 0.1, 50% of debt or all debt including interest",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print amount of each account without repaying",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginRepay(c)
+			},
+		},
+		{
+			Name:  "list-margin-loan",
+			Usage: "list margin loan records",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: BTC",
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Value: 10,
+					Usage: "max number of records",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listMarginLoans(c)
+			},
+		},
+		{
+			Name:  "list-margin-repay",
+			Usage: "list margin repay records",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: BTC",
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Value: 10,
+					Usage: "max number of records",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listMarginRepays(c)
+			},
+		},
+		{
+			Name:  "margin-create-order",
+			Usage: "create LIMIT margin order",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BNBUSDT",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "side type: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "quantity",
+					Usage: "quantity of symbol: 20.120 or 50% of free balance, plus max borrowable with MARGIN_BUY",
+				},
+				cli.StringFlag{
+					Name:  "price",
+					Usage: "price of symbol, absolute or relative to bid, ask, mid, last or avg price: bid, ask-0.1%, mid+3ticks, last*0.98",
+				},
+				cli.StringFlag{
+					Name:  "side-effect",
+					Usage: "side effect type: NO_SIDE_EFFECT, MARGIN_BUY or AUTO_REPAY",
+					Value: "NO_SIDE_EFFECT",
+				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "generate client order id from tag, account, symbol and side",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print computed order of each account without creating it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginCreateOrder(c)
+			},
+		},
+		{
+			Name:  "margin-cancel-order",
+			Usage: "cancel margin open orders",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "cancel open orders with symbol",
+				},
+				cli.Int64Flag{
+					Name:  "order-id, id",
+					Usage: "cancel open order with order id of account set by --name, filters are not allowed with it",
+				},
+				cli.StringFlag{
+					Name:  "client-id-prefix",
+					Usage: "cancel open orders with client order id prefix",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "cancel open orders with side: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "cancel open orders with order type: LIMIT, LIMIT_MAKER ...",
+				},
+				cli.StringFlag{
+					Name:  "price-above",
+					Usage: "cancel open orders with price above",
+				},
+				cli.StringFlag{
+					Name:  "price-below",
+					Usage: "cancel open orders with price below",
+				},
+				cli.DurationFlag{
+					Name:  "older-than",
+					Usage: "cancel open orders created before duration: 30m, 2h ...",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "list matching orders without canceling",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginCancelOrders(c)
+			},
+		},
+		{
+			Name:  "margin-list-order",
+			Usage: "list margin open orders",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "list orders with symbol",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "list all margin orders of symbol",
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Usage: "limit num of orders",
+				},
+				cli.StringFlag{
+					Name:  "client-id-prefix",
+					Usage: "list open orders with client order id prefix",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginListOrders(c)
+			},
+		},
+		{
+			Name:  "margin-list-trade",
+			Usage: "list margin trades",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name",
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Usage: "limit num of trades",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginListTrades(c)
+			},
+		},
+		{
+			Name:  "margin-guard",
+			Usage: "watch margin level, warn and deleverage when critical",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "warn",
+					Usage: "margin level to warn at, overrides margin_guard of config file",
+					Value: "1.5",
+				},
+				cli.StringFlag{
+					Name:  "critical",
+					Usage: "margin level to cancel margin orders and repay loans at",
+					Value: "1.2",
+				},
+				cli.StringFlag{
+					Name:  "target",
+					Usage: "margin level to restore when critical",
+					Value: "2",
+				},
+				cli.StringFlag{
+					Name:  "collateral",
+					Usage: "asset to sell with AUTO_REPAY when free balance is not enough: BTC",
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "interval to check margin level",
+					Value: time.Minute,
+				},
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "check margin level once and exit",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginGuard(c)
+			},
+		},
+		{
+			Name:  "margin-interest",
+			Usage: "report interest paid and accrued by margin loans",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "assets",
+					Usage: "report assets BTC, USDT ..., default to assets borrowed now",
+				},
+				cli.StringFlag{
+					Name:  "start",
+					Usage: "start date: 2020-12-01, default to 30 days before end",
+				},
+				cli.StringFlag{
+					Name:  "end",
+					Usage: "end date: 2020-12-31 inclusive, default to now",
+				},
+				cli.StringFlag{
+					Name:  "quote",
+					Usage: "asset to value interest in at historical prices",
+					Value: "USDT",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return marginInterest(c)
+			},
+		},
+		{
+			Name:  "futures-balance",
+			Usage: "list USDT-M futures balances",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "assets",
+					Usage: "list balances with asset USDT, BNB ..., default to non-zero balances",
+				},
+				cli.BoolTFlag{
+					Name:  "total",
+					Usage: "show total balance",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listFuturesBalances(c)
+			},
+		},
+		{
+			Name:  "futures-position",
+			Usage: "list USDT-M futures positions",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "list positions with symbol",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "list positions with zero amount too",
+				},
+				cli.BoolTFlag{
+					Name:  "total",
+					Usage: "show total positions",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listFuturesPositions(c)
+			},
+		},
+		{
+			Name:  "futures-create-order",
+			Usage: "create USDT-M futures order",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BTCUSDT",
+				},
+				cli.StringFlag{
+					Name:  "side",
+					Usage: "side type: SELL or BUY",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "order type: LIMIT, MARKET, STOP, TAKE_PROFIT, STOP_MARKET, TAKE_PROFIT_MARKET or TRAILING_STOP_MARKET",
+					Value: "LIMIT",
+				},
+				cli.StringFlag{
+					Name:  "position-side",
+					Usage: "position side: BOTH in one-way mode, LONG or SHORT in hedge mode",
+					Value: "BOTH",
+				},
+				cli.StringFlag{
+					Name:  "quantity",
+					Usage: "quantity of symbol: 0.012 or 50% of available margin times leverage",
+				},
+				cli.StringFlag{
+					Name:  "price",
+					Usage: "price of LIMIT, STOP and TAKE_PROFIT order",
+				},
+				cli.StringFlag{
+					Name:  "stop-price",
+					Usage: "stop price of STOP, TAKE_PROFIT, STOP_MARKET and TAKE_PROFIT_MARKET order",
+				},
+				cli.StringFlag{
+					Name:  "activation-price",
+					Usage: "activation price of TRAILING_STOP_MARKET order",
+				},
+				cli.StringFlag{
+					Name:  "callback-rate",
+					Usage: "callback rate in percent of TRAILING_STOP_MARKET order: 1",
+				},
+				cli.StringFlag{
+					Name:  "working-type",
+					Usage: "price triggering stop orders: CONTRACT_PRICE or MARK_PRICE",
+				},
+				cli.StringFlag{
+					Name:  "time-in-force",
+					Usage: "time in force of LIMIT, STOP and TAKE_PROFIT order: GTC, IOC, FOK or GTX",
+					Value: "GTC",
+				},
+				cli.BoolFlag{
+					Name:  "reduce-only",
+					Usage: "only reduce position, not allowed in hedge mode",
+				},
+				cli.BoolFlag{
+					Name:  "close-position",
+					Usage: "close current position of symbol and position side, whole position on trigger of STOP_MARKET and TAKE_PROFIT_MARKET, quantity is not allowed",
+				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "generate client order id from tag, account, symbol and side",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print computed order of each account without creating it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresCreateOrder(c)
+			},
+		},
+		{
+			Name:  "futures-cancel-order",
+			Usage: "cancel USDT-M futures open orders of symbol",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "cancel open orders with symbol",
+				},
+				cli.Int64Flag{
+					Name:  "id",
+					Usage: "cancel order with id of account set by --name, all open orders of symbol are canceled if not set",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print orders to cancel without canceling them",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresCancelOrders(c)
+			},
+		},
+		{
+			Name:  "futures-list-order",
+			Usage: "list USDT-M futures open orders",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "list orders with symbol",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "list all futures orders of symbol",
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Usage: "limit num of orders",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresListOrders(c)
+			},
+		},
+		{
+			Name:  "futures-leverage",
+			Usage: "change USDT-M futures initial leverage of symbol",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BTCUSDT",
+				},
+				cli.IntFlag{
+					Name:  "leverage",
+					Usage: "initial leverage allowed by leverage brackets of symbol: 5",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print current and target leverage without changing it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresLeverage(c)
+			},
+		},
+		{
+			Name:      "futures-margin-type",
+			Usage:     "change USDT-M futures margin type of symbol",
+			ArgsUsage: "ISOLATED|CROSSED",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BTCUSDT",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print current and target margin type without changing it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresMarginType(c)
+			},
+		},
+		{
+			Name:      "futures-position-mode",
+			Usage:     "change USDT-M futures position mode",
+			ArgsUsage: "hedge|oneway",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print current and target position mode without changing it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresPositionMode(c)
+			},
+		},
+		{
+			Name:      "futures-position-margin",
+			Usage:     "add or reduce margin of USDT-M futures isolated position",
+			ArgsUsage: "add|reduce",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name: BTCUSDT",
+				},
+				cli.StringFlag{
+					Name:  "position-side",
+					Usage: "position side: BOTH in one-way mode, LONG or SHORT in hedge mode",
+					Value: "BOTH",
+				},
+				cli.StringFlag{
+					Name:  "amount",
+					Usage: "margin amount: 100",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print current and target isolated margin without changing it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresPositionMargin(c)
+			},
+		},
+		{
+			Name:  "futures-income",
+			Usage: "report USDT-M futures income of realized PnL, funding fees, commissions and transfers",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "report income of symbol",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "report income of type: REALIZED_PNL, FUNDING_FEE, COMMISSION, TRANSFER ..., default to all types",
+				},
+				cli.StringFlag{
+					Name:  "start",
+					Usage: "start date: 2020-12-01, default to 7 days before end",
+				},
+				cli.StringFlag{
+					Name:  "end",
+					Usage: "end date: 2020-12-31 inclusive, default to now",
+				},
+				cli.StringFlag{
+					Name:  "group-by",
+					Usage: "sum income by comma separated fields of account, symbol, type and day, always by asset",
+					Value: "account,symbol,type",
+				},
+				cli.StringFlag{
+					Name:  "csv",
+					Usage: "export income records to csv file",
+				},
+				cli.StringFlag{
+					Name:  "summary-csv",
+					Usage: "export summed income to csv file",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return futuresIncome(c)
+			},
+		},
+		{
+			Name:  "funding-rate",
+			Usage: "list USDT-M perpetual funding rate history of symbol, or average of all perpetual symbols",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "list funding rate history of symbol, default to average of all perpetual symbols",
+				},
+				cli.StringFlag{
+					Name:  "start",
+					Usage: "start date: 2020-12-01, default to 7 days before end",
+				},
+				cli.StringFlag{
+					Name:  "end",
+					Usage: "end date: 2020-12-31 inclusive, default to now",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "sort average funding rates by symbol or funding",
+					Value: "funding",
+				},
+				cli.BoolFlag{
+					Name:  "desc",
+					Usage: "sort in descending order",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listFundingRates(c)
+			},
+		},
+		{
+			Name:  "mark-price",
+			Usage: "list USDT-M perpetual mark price, funding rate, next funding time and basis vs spot price",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "list mark price of symbol, default to all perpetual symbols",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "sort by symbol, funding or basis",
+					Value: "funding",
+				},
+				cli.BoolFlag{
+					Name:  "desc",
+					Usage: "sort in descending order",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listMarkPrices(c)
+			},
+		},
+		{
+			Name:  "transfer",
+			Usage: "transfer asset between spot and margin, futures or delivery wallet",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "source wallet: spot, margin, futures or delivery",
+					Value: "spot",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "target wallet: spot, margin, futures or delivery",
+				},
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: USDT",
+				},
+				cli.StringFlag{
+					Name:  "amount",
+					Usage: "amount to transfer: 100, 50% or all of transferable amount",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print transfer of each account without sending it",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return transfer(c)
+			},
+		},
+		{
+			Name:    "futures-list-transfer",
+			Aliases: []string{"list-transfer"},
+			Usage:   "list transfers between spot and futures or delivery wallet, margin transfer history is not listed",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "asset",
+					Usage: "asset name: USDT",
+				},
+				cli.DurationFlag{
+					Name:  "since",
+					Usage: "list transfers within the duration",
+					Value: 30 * 24 * time.Hour,
+				},
+				cli.IntFlag{
+					Name:  "limit, l",
+					Value: 10,
+					Usage: "max number of records",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listFuturesTransfers(c)
+			},
+		},
+		{
+			Name:  "get-order",
+			Usage: "get order by order id or client order id",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name",
+				},
+				cli.Int64Flag{
+					Name:  "order-id, id",
+					Usage: "order id, only unique within an account so --name is required",
+				},
+				cli.StringFlag{
+					Name:  "client-id",
+					Usage: "client order id",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return getOrder(c)
+			},
+		},
+		{
+			Name:  "wait-order",
+			Usage: "wait until order is filled, canceled, expired or rejected, exit with error if rejected",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "symbol name",
+				},
+				cli.Int64Flag{
+					Name:  "order-id, id",
+					Usage: "order id, only unique within an account so --name is required",
+				},
+				cli.StringFlag{
+					Name:  "client-id",
+					Usage: "client order id",
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "polling interval",
+					Value: time.Second,
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "max time to wait",
+					Value: 5 * time.Minute,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return waitOrder(c)
+			},
+		},
+		{
+			Name:  "panic",
+			Usage: "cancel all spot, margin and futures open orders",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "close-positions",
+					Usage: "close USDT-M and COIN-M futures positions with market orders",
+				},
+				cli.BoolFlag{
+					Name:  "repay",
+					Usage: "repay margin loans with free balance",
+				},
+				cli.StringSliceFlag{
+					Name:  "skip",
+					Usage: "skip steps: spot_orders, margin_orders, futures_orders, delivery_orders, futures_positions, delivery_positions, margin_loans",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "retry until clean or timeout",
+					Value: time.Minute,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return panicAll(c)
+			},
+		},
 	}
 	err := app.Run(os.Args)
 	if err != nil {
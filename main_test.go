@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountError(t *testing.T) {
+	assert := assert.New(t)
+	err := errors.New("order does not exist")
+	out, e := json.Marshal(map[string]interface{}{"a1": &accountError{err: err}})
+	assert.NoError(e)
+	assert.Equal(`{"a1":"error: order does not exist"}`, string(out))
+	assert.Equal(err, resultError(&accountError{err: err}))
+	assert.Error(resultError("done"))
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"strings"
+
 	binance "github.com/adshao/go-binance/v2"
 	"github.com/juju/errors"
 	"github.com/shopspring/decimal"
@@ -110,3 +112,217 @@ func listMarginBalances(c *cli.Context) error {
 		return []interface{}{results, res}, nil
 	})
 }
+
+// MarginTransaction define a margin borrow or repay of account
+type MarginTransaction struct {
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+	// Sizing describes how amount is computed, empty for absolute amount
+	Sizing string `json:"sizing,omitempty"`
+	TranID int64  `json:"tran_id,omitempty"`
+}
+
+// parseMarginAmount parse absolute amount or percent amount up to 100%,
+// percent amount is returned as ratio
+func parseMarginAmount(amount string) (decimal.Decimal, bool, error) {
+	isPct := strings.HasSuffix(amount, "%")
+	v, err := decimal.NewFromString(strings.TrimSuffix(amount, "%"))
+	if err != nil || !v.IsPositive() {
+		return decimal.Decimal{}, false, errors.Errorf("invalid amount %q", amount)
+	}
+	if !isPct {
+		return v, false, nil
+	}
+	if v.GreaterThan(decimal.NewFromInt(100)) {
+		return decimal.Decimal{}, false, errors.Errorf("invalid amount %q, percent is over 100%%", amount)
+	}
+	return v.Div(decimal.NewFromInt(100)), true, nil
+}
+
+// BorrowAmount resolve absolute amount or percent of max borrowable of asset
+func BorrowAmount(asset, amount string, maxBorrowable func() (string, error)) (*MarginTransaction, error) {
+	t := &MarginTransaction{Asset: asset, Amount: amount}
+	v, isPct, err := parseMarginAmount(amount)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if isPct {
+		max, err := maxBorrowable()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		maxDec, err := decimal.NewFromString(max)
+		if err != nil {
+			return nil, errors.Errorf("invalid max borrowable %q of %s", max, asset)
+		}
+		v = maxDec.Mul(v).Truncate(8)
+		t.Amount = v.String()
+		t.Sizing = amount + " of max borrowable " + max
+	}
+	if !v.IsPositive() {
+		return nil, errors.Errorf("nothing to borrow of %s", asset)
+	}
+	return t, nil
+}
+
+// RepayAmount resolve absolute amount, percent of debt or all debt including
+// interest of asset, amount of debt is capped by free balance of asset
+func RepayAmount(asset, amount string, marginAsset func() (*binance.UserAsset, error)) (*MarginTransaction, error) {
+	t := &MarginTransaction{Asset: asset, Amount: amount}
+	isAll := strings.EqualFold(amount, "all")
+	var ratio decimal.Decimal
+	if !isAll {
+		v, isPct, err := parseMarginAmount(amount)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !isPct {
+			return t, nil
+		}
+		ratio = v
+	}
+	userAsset, err := marginAsset()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var values [3]decimal.Decimal
+	for i, v := range []string{userAsset.Borrowed, userAsset.Interest, userAsset.Free} {
+		values[i], err = decimal.NewFromString(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid margin balance %q of %s", v, asset)
+		}
+	}
+	debt := values[0].Add(values[1])
+	free := values[2]
+	t.Sizing = "debt " + debt.String()
+	if !isAll {
+		debt = debt.Mul(ratio).Truncate(8)
+		t.Sizing = amount + " of " + t.Sizing
+	}
+	if debt.GreaterThan(free) {
+		debt = free
+		t.Sizing += ", capped by free " + free.String()
+	}
+	if !debt.IsPositive() {
+		return nil, errors.Errorf("nothing to repay of %s", asset)
+	}
+	t.Amount = debt.String()
+	return t, nil
+}
+
+// borrowAmount resolve amount to borrow of asset against max borrowable
+func (account *Account) borrowAmount(asset, amount string) (*MarginTransaction, error) {
+	return BorrowAmount(asset, amount, func() (string, error) {
+		return account.GetMaxBorrowable(asset)
+	})
+}
+
+// repayAmount resolve amount to repay of asset against margin account
+func (account *Account) repayAmount(asset, amount string) (*MarginTransaction, error) {
+	return RepayAmount(asset, amount, func() (*binance.UserAsset, error) {
+		marginAccount, err := account.GetMarginAccount()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		userAsset, ok := (&MarginAccount{Margin: marginAccount}).MarginAssets()[asset]
+		if !ok {
+			return nil, errors.Errorf("margin asset %s not found", asset)
+		}
+		return &userAsset, nil
+	})
+}
+
+// marginTransact compute transaction of each account, confirm and send it
+func marginTransact(c *cli.Context, verb string,
+	compute func(*Account, string, string) (*MarginTransaction, error),
+	send func(*Account, string, string) (int64, error)) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	asset := strings.ToUpper(c.String("asset"))
+	amount := c.String("amount")
+	if asset == "" || amount == "" {
+		return errors.New("asset and amount are required")
+	}
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			t, err := compute(account, asset, amount)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return t, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	numAccounts := 0
+	total := decimal.Decimal{}
+	for _, res := range results {
+		if t, ok := res.(*MarginTransaction); ok {
+			total = total.Add(decimal.RequireFromString(t.Amount))
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numAccounts > 0 {
+		if err := confirm("%s %s %s on %d accounts?", verb, total, asset, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			t, ok := results[account.Name].(*MarginTransaction)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			tranID, err := send(account, t.Asset, t.Amount)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			t.TranID = tranID
+			return t, nil
+		})
+}
+
+func marginBorrow(c *cli.Context) error {
+	return marginTransact(c, "borrow", (*Account).borrowAmount, (*Account).MarginBorrow)
+}
+
+func marginRepay(c *cli.Context) error {
+	return marginTransact(c, "repay", (*Account).repayAmount, (*Account).MarginRepay)
+}
+
+func listMarginLoans(c *cli.Context) error {
+	asset := strings.ToUpper(c.String("asset"))
+	limit := c.Int("limit")
+	if asset == "" {
+		return errors.New("asset is required")
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			loans, err := account.ListMarginLoans(asset, limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return loans, nil
+		})
+}
+
+func listMarginRepays(c *cli.Context) error {
+	asset := strings.ToUpper(c.String("asset"))
+	limit := c.Int("limit")
+	if asset == "" {
+		return errors.New("asset is required")
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			repays, err := account.ListMarginRepays(asset, limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return repays, nil
+		})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBorrowAmount(t *testing.T) {
+	tests := []struct {
+		amount        string
+		maxBorrowable string
+		expected      string
+		err           bool
+	}{
+		{"1.5", "10", "1.5", false},
+		{"50%", "10", "5", false},
+		{"100%", "0.123456789", "0.12345678", false},
+		{"50%", "0", "", true},
+		{"abc", "10", "", true},
+		{"-1", "10", "", true},
+		{"0", "10", "", true},
+		{"150%", "10", "", true},
+		{"x%", "10", "", true},
+		{"50%", "abc", "", true},
+	}
+	for _, test := range tests {
+		res, err := BorrowAmount("BTC", test.amount, func() (string, error) {
+			return test.maxBorrowable, nil
+		})
+		if test.err {
+			assert.Error(t, err, "%+v", test)
+			continue
+		}
+		assert.NoError(t, err, "%+v", test)
+		assert.Equal(t, test.expected, res.Amount, "%+v", test)
+	}
+
+	_, err := BorrowAmount("BTC", "50%", func() (string, error) {
+		return "", errors.New("margin account does not exist")
+	})
+	assert.Error(t, err)
+}
+
+func TestRepayAmount(t *testing.T) {
+	userAsset := &binance.UserAsset{Asset: "BTC", Borrowed: "2", Interest: "0.01", Free: "1.5"}
+	tests := []struct {
+		amount    string
+		userAsset *binance.UserAsset
+		expected  string
+		err       bool
+	}{
+		{"1", userAsset, "1", false},
+		{"50%", userAsset, "1.005", false},
+		// all debt is capped by free balance
+		{"all", userAsset, "1.5", false},
+		{"ALL", userAsset, "1.5", false},
+		{"All", &binance.UserAsset{Borrowed: "1", Interest: "0.01", Free: "3"}, "1.01", false},
+		{"all", &binance.UserAsset{Borrowed: "0", Interest: "0", Free: "3"}, "", true},
+		{"all", &binance.UserAsset{Borrowed: "", Interest: "0", Free: "3"}, "", true},
+		{"abc", userAsset, "", true},
+		{"-1%", userAsset, "", true},
+		{"101%", userAsset, "", true},
+	}
+	for _, test := range tests {
+		res, err := RepayAmount("BTC", test.amount, func() (*binance.UserAsset, error) {
+			return test.userAsset, nil
+		})
+		if test.err {
+			assert.Error(t, err, "%+v", test)
+			continue
+		}
+		assert.NoError(t, err, "%+v", test)
+		assert.Equal(t, test.expected, res.Amount, "%+v", test)
+	}
+}
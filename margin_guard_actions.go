@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// states of margin level checked by margin-guard
+const (
+	marginStateOK       = "ok"
+	marginStateWarn     = "warn"
+	marginStateCritical = "critical"
+)
+
+// MarginGuardStatus define latest margin level of account and actions taken
+// to restore it
+type MarginGuardStatus struct {
+	Level   string   `json:"level"`
+	State   string   `json:"state"`
+	Actions []string `json:"actions,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// record log action of account and keep it in status
+func (s *MarginGuardStatus) record(name, format string, args ...interface{}) {
+	action := fmt.Sprintf(format, args...)
+	log.Printf("%s: %s", name, action)
+	s.Actions = append(s.Actions, action)
+}
+
+// DeleverageAmount return value of collateral to sell for repaying loans so
+// that margin level rises to target, selling and repaying x gives margin
+// level (asset-x)/(liability-x)
+func DeleverageAmount(asset, liability, target decimal.Decimal) decimal.Decimal {
+	one := decimal.NewFromInt(1)
+	if !liability.IsPositive() || !target.GreaterThan(one) || asset.Div(liability).GreaterThanOrEqual(target) {
+		return decimal.Decimal{}
+	}
+	x := target.Mul(liability).Sub(asset).Div(target.Sub(one))
+	if x.GreaterThan(liability) {
+		x = liability
+	}
+	return x
+}
+
+// marginLevelState return state of margin level against thresholds
+func marginLevelState(level, warn, critical decimal.Decimal) string {
+	switch {
+	case level.LessThanOrEqual(critical):
+		return marginStateCritical
+	case level.LessThanOrEqual(warn):
+		return marginStateWarn
+	}
+	return marginStateOK
+}
+
+// deleverage cancel margin open orders and repay loans from free balance,
+// then sell collateral with AUTO_REPAY if margin level is still below target
+func (account *Account) deleverage(target decimal.Decimal, collateral string, status *MarginGuardStatus) error {
+	orders, err := account.ListMarginOpenOrders("")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, order := range orders {
+		if err := account.CancelMarginOrder(order.Symbol, order.OrderID); err != nil {
+			return errors.Trace(err)
+		}
+		status.record(account.Name, "canceled margin order %s %d", order.Symbol, order.OrderID)
+	}
+
+	marginAccount, err := account.GetMarginAccount()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, userAsset := range marginAccount.UserAssets {
+		debt := decimal.RequireFromString(userAsset.Borrowed).Add(decimal.RequireFromString(userAsset.Interest))
+		amount := decimal.Min(debt, decimal.RequireFromString(userAsset.Free))
+		if !amount.IsPositive() {
+			continue
+		}
+		tranID, err := account.MarginRepay(userAsset.Asset, amount.String())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		status.record(account.Name, "repaid %s %s from free balance, tran id %d", amount, userAsset.Asset, tranID)
+	}
+
+	marginAccount, err = account.GetMarginAccount()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	totalAsset := decimal.RequireFromString(marginAccount.TotalAssetOfBTC)
+	totalLiability := decimal.RequireFromString(marginAccount.TotalLiabilityOfBTC)
+	value := DeleverageAmount(totalAsset, totalLiability, target)
+	if value.IsZero() {
+		return nil
+	}
+	if collateral == "" {
+		status.record(account.Name, "margin level %s is still below target %s, no collateral to sell", marginAccount.MarginLevel, target)
+		return nil
+	}
+	return errors.Trace(account.sellCollateral(marginAccount.UserAssets, collateral, value, status))
+}
+
+// collateralSymbol return symbol and side of the order selling collateral
+// for debt asset, collateral is sold as base asset or spent as quote asset
+func collateralSymbol(symbols map[string]binance.Symbol, collateral, debtAsset string) (string, string, error) {
+	if _, ok := symbols[collateral+debtAsset]; ok {
+		return collateral + debtAsset, "SELL", nil
+	}
+	if _, ok := symbols[debtAsset+collateral]; ok {
+		return debtAsset + collateral, "BUY", nil
+	}
+	return "", "", errors.Errorf("symbol of %s and %s not found", collateral, debtAsset)
+}
+
+// sellCollateral sell collateral worth value in BTC against the asset with
+// the largest debt, the proceeds repay the debt with AUTO_REPAY
+func (account *Account) sellCollateral(userAssets []binance.UserAsset, collateral string, value decimal.Decimal,
+	status *MarginGuardStatus) error {
+	prices, err := account.ListPrices("")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	priceMap := make(map[string]string)
+	for _, p := range prices {
+		priceMap[p.Symbol] = p.Price
+	}
+	var debtAsset string
+	var free decimal.Decimal
+	maxDebt := decimal.Decimal{}
+	for _, userAsset := range userAssets {
+		if userAsset.Asset == collateral {
+			free = decimal.RequireFromString(userAsset.Free)
+			continue
+		}
+		debt := decimal.RequireFromString(userAsset.Borrowed).Add(decimal.RequireFromString(userAsset.Interest))
+		debtValue := EquityValue(map[string]decimal.Decimal{userAsset.Asset: debt}, priceMap, "BTC")
+		if debtValue.GreaterThan(maxDebt) {
+			debtAsset = userAsset.Asset
+			maxDebt = debtValue
+		}
+	}
+	if debtAsset == "" {
+		return errors.Errorf("no debt to repay by selling %s", collateral)
+	}
+	if err := account.loadSymbols(); err != nil {
+		return errors.Trace(err)
+	}
+	symbol, side, err := collateralSymbol(symbols, collateral, debtAsset)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// SELL quantity is of collateral, BUY quantity is of debt asset
+	base := collateral
+	maxAmount := free
+	if side == "BUY" {
+		base = debtAsset
+		price, ok := priceMap[symbol]
+		if !ok || !decimal.RequireFromString(price).IsPositive() {
+			return errors.Errorf("price of %s not found", symbol)
+		}
+		maxAmount = free.Div(decimal.RequireFromString(price))
+	}
+	unitValue := EquityValue(map[string]decimal.Decimal{base: decimal.NewFromInt(1)}, priceMap, "BTC")
+	if !unitValue.IsPositive() {
+		return errors.Errorf("price of %s in BTC not found", base)
+	}
+	quantity, err := lotQuantity(symbols[symbol], decimal.Min(value.Div(unitValue), maxAmount))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !decimal.RequireFromString(quantity).IsPositive() {
+		return errors.Errorf("not enough free %s to sell", collateral)
+	}
+	res, err := account.CreateMarginMarketOrder(symbol, side, quantity, "AUTO_REPAY")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	status.record(account.Name, "%s %s %s with AUTO_REPAY to sell %s, order id %d", side, quantity, symbol, collateral, res.OrderID)
+	return nil
+}
+
+// guardMargin check margin level of account and deleverage when critical
+func (account *Account) guardMargin(warn, critical, target decimal.Decimal, collateral string, status *MarginGuardStatus) error {
+	marginAccount, err := account.GetMarginAccount()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	status.Level = marginAccount.MarginLevel
+	if !decimal.RequireFromString(marginAccount.TotalLiabilityOfBTC).IsPositive() {
+		status.State = marginStateOK
+		return nil
+	}
+	status.State = marginLevelState(decimal.RequireFromString(marginAccount.MarginLevel), warn, critical)
+	switch status.State {
+	case marginStateWarn:
+		log.Printf("%s: margin level %s is below warn threshold %s", account.Name, status.Level, warn)
+	case marginStateCritical:
+		status.record(account.Name, "margin level %s is below critical threshold %s, deleveraging to %s",
+			status.Level, critical, target)
+		return errors.Trace(account.deleverage(target, collateral, status))
+	}
+	return nil
+}
+
+// thresholdValue return flag value if set, or value of config, or default
+// value of flag
+func thresholdValue(c *cli.Context, flag, configValue string) (decimal.Decimal, error) {
+	value := c.String(flag)
+	if !c.IsSet(flag) && configValue != "" {
+		value = configValue
+	}
+	v, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Decimal{}, errors.Errorf("invalid %s %s", flag, value)
+	}
+	return v, nil
+}
+
+func marginGuard(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	guardConfig := config.MarginGuard
+	warn, err := thresholdValue(c, "warn", guardConfig.Warn)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	critical, err := thresholdValue(c, "critical", guardConfig.Critical)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	target, err := thresholdValue(c, "target", guardConfig.Target)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	collateral := c.String("collateral")
+	if !c.IsSet("collateral") {
+		collateral = guardConfig.Collateral
+	}
+	if !critical.LessThan(warn) || !target.GreaterThan(critical) || !critical.GreaterThan(decimal.NewFromInt(1)) {
+		return errors.New("thresholds must satisfy 1 < critical < warn and critical < target")
+	}
+	interval := c.Duration("interval")
+	numAccounts := len(findAccounts(name))
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if err := confirm("guard margin level of %d accounts, canceling orders, repaying loans and selling %q when critical?",
+		numAccounts, collateral); err != nil {
+		return errors.Trace(err)
+	}
+
+	statuses := make(map[string]*MarginGuardStatus)
+	stopC := make(chan os.Signal, 1)
+	signal.Notify(stopC, os.Interrupt, syscall.SIGTERM)
+	for {
+		for _, account := range findAccounts(name) {
+			status, ok := statuses[account.Name]
+			if !ok {
+				status = new(MarginGuardStatus)
+				statuses[account.Name] = status
+			}
+			status.Error = ""
+			if err := account.guardMargin(warn, critical, target, collateral, status); err != nil {
+				status.Error = err.Error()
+				log.Printf("%s: failed to guard margin: %s", account.Name, err)
+			}
+		}
+		if c.Bool("once") || !sleepUntil(time.Now().Add(interval), stopC) {
+			return print(statuses)
+		}
+	}
+}
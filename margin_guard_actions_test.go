@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleverageAmount(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name      string
+		asset     string
+		liability string
+		target    string
+		expect    string
+	}{
+		{
+			name:      "test with level below target",
+			asset:     "12",
+			liability: "10",
+			target:    "2",
+			expect:    "8",
+		},
+		{
+			name:      "test with level above target",
+			asset:     "30",
+			liability: "10",
+			target:    "2",
+			expect:    "0",
+		},
+		{
+			name:      "test with no liability",
+			asset:     "30",
+			liability: "0",
+			target:    "2",
+			expect:    "0",
+		},
+		{
+			name:      "test with amount capped by liability",
+			asset:     "9",
+			liability: "10",
+			target:    "2",
+			expect:    "10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := DeleverageAmount(decimal.RequireFromString(tt.asset), decimal.RequireFromString(tt.liability),
+				decimal.RequireFromString(tt.target))
+			assert.Equal(tt.expect, x.String())
+		})
+	}
+}
+
+func TestMarginLevelState(t *testing.T) {
+	assert := assert.New(t)
+	warn := decimal.RequireFromString("1.5")
+	critical := decimal.RequireFromString("1.2")
+	assert.Equal(marginStateOK, marginLevelState(decimal.RequireFromString("2"), warn, critical))
+	assert.Equal(marginStateWarn, marginLevelState(decimal.RequireFromString("1.4"), warn, critical))
+	assert.Equal(marginStateCritical, marginLevelState(decimal.RequireFromString("1.2"), warn, critical))
+}
+
+func TestCollateralSymbol(t *testing.T) {
+	symbols := map[string]binance.Symbol{"BTCUSDT": {Symbol: "BTCUSDT"}, "BNBBTC": {Symbol: "BNBBTC"}}
+	tests := []struct {
+		collateral, debt string
+		symbol, side     string
+	}{
+		{"BTC", "USDT", "BTCUSDT", "SELL"},
+		{"USDT", "BTC", "BTCUSDT", "BUY"},
+		{"BTC", "BNB", "BNBBTC", "BUY"},
+		{"USDT", "BNB", "", ""},
+	}
+	for _, test := range tests {
+		symbol, side, err := collateralSymbol(symbols, test.collateral, test.debt)
+		if test.symbol == "" {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.symbol, symbol)
+		assert.Equal(t, test.side, side)
+	}
+}
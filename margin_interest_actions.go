@@ -0,0 +1,285 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	hourMillis = int64(time.Hour / time.Millisecond)
+	yearMillis = int64(365 * 24 * time.Hour / time.Millisecond)
+)
+
+// LoanEvent define change of outstanding principal at time in milliseconds,
+// principal is positive for loan and negative for repay
+type LoanEvent struct {
+	Time      int64
+	Principal decimal.Decimal
+}
+
+// InterestReport define interest cost of margin loan of asset in a date range
+type InterestReport struct {
+	Asset        string `json:"asset"`
+	Quote        string `json:"quote"`
+	Paid         string `json:"paid"`
+	PaidValue    string `json:"paid_value"`
+	Accrued      string `json:"accrued"`
+	AccruedValue string `json:"accrued_value"`
+	TotalValue   string `json:"total_value"`
+	// AveragePrincipal is time weighted outstanding principal of the range
+	AveragePrincipal string `json:"average_principal"`
+	AnnualizedRate   string `json:"annualized_rate"`
+	// Loans and Repays are numbers of loans and repays in the range
+	Loans  int `json:"loans"`
+	Repays int `json:"repays"`
+}
+
+// Active check if asset is borrowed in the range, by a loan or repay in it,
+// principal outstanding during it or interest accrued at its end
+func (r *InterestReport) Active() bool {
+	return r.Loans > 0 || r.Repays > 0 ||
+		decimal.RequireFromString(r.AveragePrincipal).IsPositive() ||
+		decimal.RequireFromString(r.Accrued).IsPositive()
+}
+
+// parseAmount parse decimal field of API response
+func parseAmount(name, v string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return decimal.Decimal{}, errors.Errorf("invalid %s %q", name, v)
+	}
+	return d, nil
+}
+
+// overlapMillis return length of intersection of (a, b) and (start, end)
+func overlapMillis(a, b, start, end int64) int64 {
+	if a < start {
+		a = start
+	}
+	if b > end {
+		b = end
+	}
+	if b < a {
+		return 0
+	}
+	return b - a
+}
+
+// AverageOutstanding return time weighted average principal between start and
+// end, principal before each event is derived backward from current principal
+// at now
+func AverageOutstanding(current decimal.Decimal, events []LoanEvent, start, end, now int64) decimal.Decimal {
+	if end <= start {
+		return decimal.Decimal{}
+	}
+	sorted := make([]LoanEvent, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Time > sorted[j].Time
+	})
+	area := decimal.Decimal{}
+	balance := current
+	t := now
+	for _, e := range sorted {
+		if balance.IsPositive() {
+			area = area.Add(balance.Mul(decimal.NewFromInt(overlapMillis(e.Time, t, start, end))))
+		}
+		balance = balance.Sub(e.Principal)
+		t = e.Time
+	}
+	if balance.IsPositive() {
+		area = area.Add(balance.Mul(decimal.NewFromInt(overlapMillis(start, t, start, end))))
+	}
+	return area.Div(decimal.NewFromInt(end - start))
+}
+
+// AnnualizedRate return interest divided by average principal, scaled from
+// the duration in milliseconds to a year
+func AnnualizedRate(interest, principal decimal.Decimal, duration int64) decimal.Decimal {
+	if !principal.IsPositive() || duration <= 0 {
+		return decimal.Decimal{}
+	}
+	return interest.Div(principal).Mul(decimal.NewFromInt(yearMillis)).Div(decimal.NewFromInt(duration))
+}
+
+// assetValue convert amount of asset to quote at historical price of time in
+// milliseconds, prices of hourly klines are cached by symbol and hour
+func (account *Account) assetValue(asset, quote string, amount decimal.Decimal, at int64,
+	cache map[string]decimal.Decimal) (decimal.Decimal, error) {
+	if asset == quote || amount.IsZero() {
+		return amount, nil
+	}
+	if err := account.loadSymbols(); err != nil {
+		return decimal.Decimal{}, errors.Trace(err)
+	}
+	hour := at - at%hourMillis
+	symbol, inverse := asset+quote, false
+	if _, ok := symbols[symbol]; !ok {
+		symbol, inverse = quote+asset, true
+		if _, ok := symbols[symbol]; !ok {
+			return decimal.Decimal{}, errors.Errorf("symbol of %s and %s not found", asset, quote)
+		}
+	}
+	key := symbol + "@" + decimal.NewFromInt(hour).String()
+	price, ok := cache[key]
+	if !ok {
+		p, err := account.HistoricalPrice(symbol, hour)
+		if err != nil {
+			return decimal.Decimal{}, errors.Trace(err)
+		}
+		price, err = parseAmount("price of "+symbol, p)
+		if err != nil {
+			return decimal.Decimal{}, errors.Trace(err)
+		}
+		cache[key] = price
+	}
+	if inverse {
+		if price.IsZero() {
+			return decimal.Decimal{}, errors.Errorf("invalid price of %s", symbol)
+		}
+		return amount.Div(price), nil
+	}
+	return amount.Mul(price), nil
+}
+
+// interestReport compute interest paid by repays of asset between start and
+// end, plus interest accrued but not paid yet if end is at or near now
+func (account *Account) interestReport(userAsset binance.UserAsset, quote string, start, end int64,
+	cache map[string]decimal.Decimal) (*InterestReport, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	loans, err := account.ListMarginLoansBetween(userAsset.Asset, start, now)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	repays, err := account.ListMarginRepaysBetween(userAsset.Asset, start, now)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	report := &InterestReport{Asset: userAsset.Asset, Quote: quote}
+	var events []LoanEvent
+	for _, loan := range loans {
+		principal, err := parseAmount("loan principal", loan.Principal)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		events = append(events, LoanEvent{Time: loan.Timestamp, Principal: principal})
+		if loan.Timestamp <= end {
+			report.Loans++
+		}
+	}
+	paid := decimal.Decimal{}
+	paidValue := decimal.Decimal{}
+	for _, repay := range repays {
+		principal, err := parseAmount("repay principal", repay.Principal)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		events = append(events, LoanEvent{Time: repay.Timestamp, Principal: principal.Neg()})
+		if repay.Timestamp > end {
+			continue
+		}
+		report.Repays++
+		interest, err := parseAmount("repay interest", repay.Interest)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		value, err := account.assetValue(userAsset.Asset, quote, interest, repay.Timestamp, cache)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		paid = paid.Add(interest)
+		paidValue = paidValue.Add(value)
+	}
+	// interest accrued but not paid is owed now, it belongs to the range only
+	// if the range ends at or near now
+	accrued := decimal.Decimal{}
+	accruedValue := decimal.Decimal{}
+	if end >= now-hourMillis {
+		accrued, err = parseAmount("interest", userAsset.Interest)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		accruedValue, err = account.assetValue(userAsset.Asset, quote, accrued, now, cache)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	borrowed, err := parseAmount("borrowed", userAsset.Borrowed)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	principal := AverageOutstanding(borrowed, events, start, end, now)
+	report.Paid = paid.String()
+	report.PaidValue = paidValue.String()
+	report.Accrued = accrued.String()
+	report.AccruedValue = accruedValue.String()
+	report.TotalValue = paidValue.Add(accruedValue).String()
+	report.AveragePrincipal = principal.String()
+	report.AnnualizedRate = AnnualizedRate(paid.Add(accrued), principal, end-start).String()
+	return report, nil
+}
+
+func marginInterest(c *cli.Context) error {
+	var assets []string
+	for _, asset := range c.StringSlice("assets") {
+		assets = append(assets, strings.ToUpper(asset))
+	}
+	quote := strings.ToUpper(c.String("quote"))
+	start, end, err := ParseDateRange(c.String("start"), c.String("end"), 30, time.Now())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	startMillis := start.UnixNano() / int64(time.Millisecond)
+	endMillis := end.UnixNano() / int64(time.Millisecond)
+	cache := make(map[string]decimal.Decimal)
+
+	return accountsDo(func(account *Account) (interface{}, error) {
+		marginAccount, err := account.GetMarginAccount()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		marginAssets := (&MarginAccount{Margin: marginAccount}).MarginAssets()
+		reports := make(map[string]*InterestReport)
+		for asset, userAsset := range marginAssets {
+			// assets are reported if listed, otherwise history of every asset
+			// is checked for loans in the range
+			if len(assets) > 0 && !StrContains(assets, asset) {
+				continue
+			}
+			report, err := account.interestReport(userAsset, quote, startMillis, endMillis, cache)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if len(assets) == 0 && !report.Active() {
+				continue
+			}
+			reports[asset] = report
+		}
+		return reports, nil
+	}, func(results map[string]interface{}) (interface{}, error) {
+		totals := make(map[string]decimal.Decimal)
+		total := decimal.Decimal{}
+		for name, res := range results {
+			reports, ok := res.(map[string]*InterestReport)
+			if !ok {
+				continue
+			}
+			for _, report := range reports {
+				value := decimal.RequireFromString(report.TotalValue)
+				totals[name] = totals[name].Add(value)
+				total = total.Add(value)
+			}
+		}
+		return []interface{}{results, map[string]interface{}{
+			"Accounts": totals,
+			"Total":    total,
+			"Quote":    quote,
+		}}, nil
+	})
+}
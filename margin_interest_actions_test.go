@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAverageOutstanding(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name    string
+		current string
+		events  []LoanEvent
+		expect  string
+	}{
+		{
+			name:    "test without events",
+			current: "10",
+			expect:  "10",
+		},
+		{
+			name:    "test with loan in range",
+			current: "10",
+			events: []LoanEvent{
+				{Time: 50, Principal: decimal.RequireFromString("10")},
+			},
+			expect: "5",
+		},
+		{
+			name:    "test with repay in range and loan after end",
+			current: "5",
+			events: []LoanEvent{
+				{Time: 150, Principal: decimal.RequireFromString("5")},
+				{Time: 75, Principal: decimal.RequireFromString("-4")},
+			},
+			expect: "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avg := AverageOutstanding(decimal.RequireFromString(tt.current), tt.events, 0, 100, 200)
+			assert.Equal(tt.expect, avg.String())
+		})
+	}
+}
+
+func TestInterestReportActive(t *testing.T) {
+	assert := assert.New(t)
+	report := &InterestReport{AveragePrincipal: "0", Accrued: "0"}
+	assert.False(report.Active())
+	// repaid in the range without interest
+	report.Repays = 1
+	assert.True(report.Active())
+	report = &InterestReport{AveragePrincipal: "0.5", Accrued: "0"}
+	assert.True(report.Active())
+	report = &InterestReport{AveragePrincipal: "0", Accrued: "0.001"}
+	assert.True(report.Active())
+}
+
+func TestAnnualizedRate(t *testing.T) {
+	assert := assert.New(t)
+	rate := AnnualizedRate(decimal.RequireFromString("1"), decimal.RequireFromString("100"), yearMillis/12)
+	assert.Equal("0.12", rate.String())
+	assert.True(AnnualizedRate(decimal.RequireFromString("1"), decimal.Decimal{}, yearMillis).IsZero())
+}
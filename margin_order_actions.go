@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// MarginOrderPlan define a computed margin order request of account
+type MarginOrderPlan struct {
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"`
+	Quantity   string `json:"quantity"`
+	Price      string `json:"price"`
+	PriceExpr  string `json:"price_expr,omitempty"`
+	Notional   string `json:"notional"`
+	SideEffect string `json:"side_effect"`
+	// Sizing describes how quantity is computed, empty for absolute quantity
+	Sizing        string `json:"sizing,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+	OrderID       int64  `json:"order_id,omitempty"`
+}
+
+// CheckMarginOrder check side and side effect of margin order
+func CheckMarginOrder(side, sideEffect string) error {
+	if side != "BUY" && side != "SELL" {
+		return errors.Errorf("invalid side %s", side)
+	}
+	switch binance.SideEffectType(sideEffect) {
+	case binance.SideEffectTypeNoSideEffect, binance.SideEffectTypeMarginBuy, binance.SideEffectTypeAutoRepay:
+	default:
+		return errors.Errorf("invalid side effect %s", sideEffect)
+	}
+	return nil
+}
+
+// MarginPercentQuantity return quantity of percent of free asset, plus max
+// borrowable asset if side effect is MARGIN_BUY, and describe the sizing.
+// asset is quote asset of BUY and base asset of SELL
+func MarginPercentQuantity(info binance.Symbol, side, quantity, price, sideEffect string,
+	free, borrowable decimal.Decimal) (string, string, error) {
+	asset := info.BaseAsset
+	if side == "BUY" {
+		asset = info.QuoteAsset
+	}
+	available := free
+	sizing := fmt.Sprintf("%s of free %s", quantity, free)
+	if sideEffect == string(binance.SideEffectTypeMarginBuy) {
+		available = available.Add(borrowable)
+		sizing = fmt.Sprintf("%s plus max borrowable %s", sizing, borrowable)
+	}
+	sizing = fmt.Sprintf("%s %s", sizing, asset)
+	amount := available.Mul(decimal.NewFromFloat(StrToPct(quantity)))
+	if side == "BUY" {
+		p, err := decimal.NewFromString(price)
+		if err != nil || !p.IsPositive() {
+			return "", "", errors.Errorf("invalid price %s", price)
+		}
+		amount = amount.DivRound(p, int32(info.BaseAssetPrecision))
+	}
+	newQuantity, err := lotQuantity(info, amount)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	return newQuantity, sizing, nil
+}
+
+// marginOrderQuantity resolve percent quantity against free balance of margin
+// account, plus max borrowable if the order borrows with MARGIN_BUY
+func (account *Account) marginOrderQuantity(symbol, side, quantity, price, sideEffect string) (string, string, error) {
+	if !strings.HasSuffix(quantity, "%") {
+		return quantity, "", nil
+	}
+	err := account.loadSymbols()
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return "", "", errors.Errorf("symbol %s not found", symbol)
+	}
+	asset := info.BaseAsset
+	if side == "BUY" {
+		asset = info.QuoteAsset
+	}
+	marginAccount, err := account.GetMarginAccount()
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	free := decimal.Decimal{}
+	if userAsset, ok := (&MarginAccount{Margin: marginAccount}).MarginAssets()[asset]; ok {
+		free = decimal.RequireFromString(userAsset.Free)
+	}
+	borrowable := decimal.Decimal{}
+	if sideEffect == string(binance.SideEffectTypeMarginBuy) {
+		s, err := account.GetMaxBorrowable(asset)
+		if err != nil {
+			return "", "", errors.Trace(err)
+		}
+		borrowable = decimal.RequireFromString(s)
+	}
+	return MarginPercentQuantity(info, side, quantity, price, sideEffect, free, borrowable)
+}
+
+// planMarginOrder compute margin order request of account without sending it
+func (account *Account) planMarginOrder(symbol, side, quantity, price, sideEffect, tag string) (*MarginOrderPlan, error) {
+	resolvedPrice, err := account.resolvePrice(symbol, price)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	p, err := decimal.NewFromString(resolvedPrice)
+	if err != nil || !p.IsPositive() {
+		return nil, errors.Errorf("invalid price %q", resolvedPrice)
+	}
+	newQuantity, sizing, err := account.marginOrderQuantity(symbol, side, quantity, resolvedPrice, sideEffect)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	q, err := decimal.NewFromString(newQuantity)
+	if err != nil || !q.IsPositive() {
+		return nil, errors.Errorf("invalid quantity %q", newQuantity)
+	}
+	notional := q.Mul(p)
+	plan := &MarginOrderPlan{
+		Symbol:        symbol,
+		Side:          side,
+		Quantity:      newQuantity,
+		Price:         resolvedPrice,
+		Notional:      notional.String(),
+		SideEffect:    sideEffect,
+		Sizing:        sizing,
+		ClientOrderID: ClientOrderID(tag, account.Name, symbol, side),
+	}
+	if resolvedPrice != price {
+		plan.PriceExpr = price
+	}
+	return plan, nil
+}
+
+// createMarginPlannedOrder create margin order of plan, open order already
+// created with the client order id of plan is returned instead of creating it
+// again
+func (account *Account) createMarginPlannedOrder(plan *MarginOrderPlan) (int64, error) {
+	if plan.ClientOrderID != "" {
+		order, err := account.GetMarginOrder(plan.Symbol, 0, plan.ClientOrderID)
+		if err == nil && !isOrderFinished(order) {
+			return order.OrderID, nil
+		}
+		if err != nil && !isOrderNotExist(err) {
+			return 0, errors.Trace(err)
+		}
+	}
+	res, err := account.CreateMarginOrder(plan.Symbol, plan.Side, plan.Quantity, plan.Price,
+		plan.SideEffect, plan.ClientOrderID)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.OrderID, nil
+}
+
+func marginCreateOrder(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	symbol := c.String("symbol")
+	side := strings.ToUpper(c.String("side"))
+	quantity := c.String("quantity")
+	price := c.String("price")
+	sideEffect := strings.ToUpper(c.String("side-effect"))
+	tag := c.String("tag")
+	if symbol == "" || quantity == "" || price == "" {
+		return errors.New("symbol, quantity and price are required")
+	}
+	if err := CheckMarginOrder(side, sideEffect); err != nil {
+		return errors.Trace(err)
+	}
+	if q, err := decimal.NewFromString(strings.TrimSuffix(quantity, "%")); err != nil || !q.IsPositive() {
+		return errors.Errorf("invalid quantity %q", quantity)
+	}
+	if ref, _ := PriceReference(price); ref == "" {
+		if p, err := decimal.NewFromString(price); err != nil || !p.IsPositive() {
+			return errors.Errorf("invalid price %q", price)
+		}
+	}
+	if err := CheckTag(tag); err != nil {
+		return errors.Trace(err)
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			plan, err := account.planMarginOrder(symbol, side, quantity, price, sideEffect, tag)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err := config.CheckNotional(account.Name, decimal.RequireFromString(plan.Notional)); err != nil {
+				return nil, errors.Trace(err)
+			}
+			return plan, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	var numOrders int
+	total := decimal.Decimal{}
+	for _, res := range results {
+		if plan, ok := res.(*MarginOrderPlan); ok {
+			total = total.Add(decimal.RequireFromString(plan.Notional))
+			numOrders++
+		}
+	}
+	if err := config.CheckAccounts(numOrders); err != nil {
+		return errors.Trace(err)
+	}
+	if numOrders > 0 {
+		if err := confirm("create margin orders on %d accounts with total notional %s?", numOrders, total); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			plan, ok := results[account.Name].(*MarginOrderPlan)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			orderID, err := account.createMarginPlannedOrder(plan)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			plan.OrderID = orderID
+			return plan, nil
+		})
+}
+
+func marginCancelOrders(c *cli.Context) error {
+	if c.Int64("id") != 0 && c.String("symbol") == "" {
+		return errors.New("symbol is required to cancel order by id")
+	}
+	return runCancelOrders(c, "margin orders",
+		func(account *Account, symbol string) ([]*binance.Order, error) {
+			return account.ListMarginOpenOrders(symbol)
+		},
+		func(account *Account, symbol string, orderID int64) error {
+			return account.CancelMarginOrder(symbol, orderID)
+		})
+}
+
+func marginListOrders(c *cli.Context) error {
+	symbol := c.String("symbol")
+	all := c.Bool("all")
+	limit := c.Int("limit")
+	clientIDPrefix := c.String("client-id-prefix")
+	if all && symbol == "" {
+		return errors.New("symbol is required")
+	}
+	// client order id is not returned by the margin all orders API client
+	if all && clientIDPrefix != "" {
+		return errors.New("client id prefix can not be used with all orders")
+	}
+	return accountsDo(func(account *Account) (interface{}, error) {
+		if all {
+			orders, err := account.ListMarginOrders(symbol, limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return orders, nil
+		}
+		orders, err := account.ListMarginOpenOrders(symbol)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		filter := &OrderFilter{ClientIDPrefix: clientIDPrefix}
+		return filter.Filter(orders, time.Now()), nil
+	})
+}
+
+func marginListTrades(c *cli.Context) error {
+	symbol := c.String("symbol")
+	limit := c.Int("limit")
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			trades, err := account.ListMarginTrades(symbol, limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return trades, nil
+		})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMarginOrder(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name       string
+		side       string
+		sideEffect string
+		err        bool
+	}{
+		{
+			name:       "test with no side effect",
+			side:       "BUY",
+			sideEffect: "NO_SIDE_EFFECT",
+		},
+		{
+			name:       "test with margin buy",
+			side:       "BUY",
+			sideEffect: "MARGIN_BUY",
+		},
+		{
+			name:       "test with auto repay",
+			side:       "SELL",
+			sideEffect: "AUTO_REPAY",
+		},
+		{
+			name:       "test with invalid side effect",
+			side:       "SELL",
+			sideEffect: "AUTO_BORROW",
+			err:        true,
+		},
+		{
+			name:       "test with empty side effect",
+			side:       "SELL",
+			sideEffect: "",
+			err:        true,
+		},
+		{
+			name:       "test with invalid side",
+			side:       "HOLD",
+			sideEffect: "NO_SIDE_EFFECT",
+			err:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMarginOrder(tt.side, tt.sideEffect)
+			if tt.err {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestMarginPercentQuantity(t *testing.T) {
+	assert := assert.New(t)
+	info := binance.Symbol{
+		Symbol:             "BNBUSDT",
+		BaseAsset:          "BNB",
+		QuoteAsset:         "USDT",
+		BaseAssetPrecision: 8,
+		Filters: []map[string]interface{}{
+			{"filterType": "LOT_SIZE", "minQty": "0.01", "maxQty": "1000", "stepSize": "0.01"},
+		},
+	}
+	tests := []struct {
+		name       string
+		side       string
+		quantity   string
+		price      string
+		sideEffect string
+		free       string
+		borrowable string
+		expect     string
+		sizing     string
+		err        bool
+	}{
+		{
+			name:       "test with buy of free quote",
+			side:       "BUY",
+			quantity:   "50%",
+			price:      "20",
+			sideEffect: "NO_SIDE_EFFECT",
+			free:       "1000",
+			borrowable: "3000",
+			expect:     "25",
+			sizing:     "50% of free 1000 USDT",
+		},
+		{
+			name:       "test with buy of free plus max borrowable quote",
+			side:       "BUY",
+			quantity:   "50%",
+			price:      "20",
+			sideEffect: "MARGIN_BUY",
+			free:       "1000",
+			borrowable: "3000",
+			expect:     "100",
+			sizing:     "50% of free 1000 plus max borrowable 3000 USDT",
+		},
+		{
+			name:       "test with sell of free base rounded to step",
+			side:       "SELL",
+			quantity:   "33%",
+			price:      "20",
+			sideEffect: "AUTO_REPAY",
+			free:       "10",
+			borrowable: "5",
+			expect:     "3.3",
+			sizing:     "33% of free 10 BNB",
+		},
+		{
+			name:       "test with sell of free plus max borrowable base",
+			side:       "SELL",
+			quantity:   "100%",
+			price:      "20",
+			sideEffect: "MARGIN_BUY",
+			free:       "1.005",
+			borrowable: "2",
+			expect:     "3",
+			sizing:     "100% of free 1.005 plus max borrowable 2 BNB",
+		},
+		{
+			name:       "test with buy of invalid price",
+			side:       "BUY",
+			quantity:   "50%",
+			price:      "0",
+			sideEffect: "NO_SIDE_EFFECT",
+			free:       "1000",
+			borrowable: "0",
+			err:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, sizing, err := MarginPercentQuantity(info, tt.side, tt.quantity, tt.price, tt.sideEffect,
+				decimal.RequireFromString(tt.free), decimal.RequireFromString(tt.borrowable))
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.expect, quantity)
+			assert.Equal(tt.sizing, sizing)
+		})
+	}
+}
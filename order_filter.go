@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+)
+
+// OrderFilter define conditions to select open orders, empty conditions
+// match all orders
+type OrderFilter struct {
+	Side           string
+	Type           string
+	PriceAbove     string
+	PriceBelow     string
+	OlderThan      time.Duration
+	ClientIDPrefix string
+}
+
+// Validate check price conditions of filter are valid decimals
+func (f *OrderFilter) Validate() error {
+	if f.PriceAbove != "" {
+		if _, err := decimal.NewFromString(f.PriceAbove); err != nil {
+			return errors.Errorf("invalid price above %s", f.PriceAbove)
+		}
+	}
+	if f.PriceBelow != "" {
+		if _, err := decimal.NewFromString(f.PriceBelow); err != nil {
+			return errors.Errorf("invalid price below %s", f.PriceBelow)
+		}
+	}
+	return nil
+}
+
+// IsEmpty check if filter has no condition
+func (f *OrderFilter) IsEmpty() bool {
+	return *f == OrderFilter{}
+}
+
+// Match check if order matches all conditions of filter, price conditions
+// which are not valid decimals match no order
+func (f *OrderFilter) Match(order *binance.Order, now time.Time) bool {
+	if f.Side != "" && !strings.EqualFold(string(order.Side), f.Side) {
+		return false
+	}
+	if f.Type != "" && !strings.EqualFold(string(order.Type), f.Type) {
+		return false
+	}
+	if f.ClientIDPrefix != "" && !strings.HasPrefix(order.ClientOrderID, f.ClientIDPrefix) {
+		return false
+	}
+	if f.PriceAbove != "" || f.PriceBelow != "" {
+		price, err := decimal.NewFromString(order.Price)
+		if err != nil {
+			return false
+		}
+		if f.PriceAbove != "" {
+			above, err := decimal.NewFromString(f.PriceAbove)
+			if err != nil || !price.GreaterThan(above) {
+				return false
+			}
+		}
+		if f.PriceBelow != "" {
+			below, err := decimal.NewFromString(f.PriceBelow)
+			if err != nil || !price.LessThan(below) {
+				return false
+			}
+		}
+	}
+	if f.OlderThan > 0 {
+		created := time.Unix(0, order.Time*int64(time.Millisecond))
+		if now.Sub(created) < f.OlderThan {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter return orders matching filter
+func (f *OrderFilter) Filter(orders []*binance.Order, now time.Time) []*binance.Order {
+	var l []*binance.Order
+	for _, order := range orders {
+		if f.Match(order, now) {
+			l = append(l, order)
+		}
+	}
+	return l
+}
+
+// KeepBest return orders except the n closest to mid price of each symbol
+func KeepBest(orders []*binance.Order, n int, mids map[string]decimal.Decimal) []*binance.Order {
+	bySymbol := make(map[string][]*binance.Order)
+	var orderSymbols []string
+	for _, order := range orders {
+		if _, ok := bySymbol[order.Symbol]; !ok {
+			orderSymbols = append(orderSymbols, order.Symbol)
+		}
+		bySymbol[order.Symbol] = append(bySymbol[order.Symbol], order)
+	}
+	var l []*binance.Order
+	for _, symbol := range orderSymbols {
+		symbolOrders := bySymbol[symbol]
+		mid := mids[symbol]
+		sort.SliceStable(symbolOrders, func(i, j int) bool {
+			di := decimal.RequireFromString(symbolOrders[i].Price).Sub(mid).Abs()
+			dj := decimal.RequireFromString(symbolOrders[j].Price).Sub(mid).Abs()
+			return di.LessThan(dj)
+		})
+		if len(symbolOrders) > n {
+			l = append(l, symbolOrders[n:]...)
+		}
+	}
+	return l
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderFilterMatch(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1600000000, 0)
+	order := &binance.Order{
+		Symbol:        "BNBUSDT",
+		ClientOrderID: "mm1-abc",
+		Price:         "21.5",
+		Side:          binance.SideTypeBuy,
+		Type:          binance.OrderTypeLimit,
+		Time:          now.Add(-3*time.Hour).UnixNano() / int64(time.Millisecond),
+	}
+	tests := []struct {
+		name   string
+		filter OrderFilter
+		expect bool
+	}{
+		{
+			name:   "test with empty filter",
+			filter: OrderFilter{},
+			expect: true,
+		},
+		{
+			name:   "test with side",
+			filter: OrderFilter{Side: "buy"},
+			expect: true,
+		},
+		{
+			name:   "test with other side",
+			filter: OrderFilter{Side: "SELL"},
+			expect: false,
+		},
+		{
+			name:   "test with type",
+			filter: OrderFilter{Type: "LIMIT_MAKER"},
+			expect: false,
+		},
+		{
+			name:   "test with price range",
+			filter: OrderFilter{PriceAbove: "20", PriceBelow: "22"},
+			expect: true,
+		},
+		{
+			name:   "test with price below",
+			filter: OrderFilter{PriceBelow: "21.5"},
+			expect: false,
+		},
+		{
+			name:   "test with older than",
+			filter: OrderFilter{OlderThan: 2 * time.Hour},
+			expect: true,
+		},
+		{
+			name:   "test with too young order",
+			filter: OrderFilter{OlderThan: 4 * time.Hour},
+			expect: false,
+		},
+		{
+			name:   "test with client id prefix",
+			filter: OrderFilter{ClientIDPrefix: "mm2"},
+			expect: false,
+		},
+		{
+			name:   "test with invalid price above",
+			filter: OrderFilter{PriceAbove: "abc"},
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(tt.expect, tt.filter.Match(order, now))
+		})
+	}
+}
+
+func TestOrderFilterValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&OrderFilter{}).Validate())
+	assert.NoError((&OrderFilter{PriceAbove: "20", PriceBelow: "22.5"}).Validate())
+	assert.Error((&OrderFilter{PriceAbove: "abc"}).Validate())
+	assert.Error((&OrderFilter{PriceBelow: "1,000"}).Validate())
+}
+
+func TestOrderFilterIsEmpty(t *testing.T) {
+	assert := assert.New(t)
+	assert.True((&OrderFilter{}).IsEmpty())
+	assert.False((&OrderFilter{Side: "BUY"}).IsEmpty())
+	assert.False((&OrderFilter{OlderThan: time.Hour}).IsEmpty())
+}
+
+func TestKeepBest(t *testing.T) {
+	assert := assert.New(t)
+	orders := []*binance.Order{
+		{Symbol: "BNBUSDT", OrderID: 1, Price: "19"},
+		{Symbol: "BNBUSDT", OrderID: 2, Price: "20.5"},
+		{Symbol: "BNBUSDT", OrderID: 3, Price: "22"},
+		{Symbol: "BNBBTC", OrderID: 4, Price: "0.002"},
+	}
+	mids := map[string]decimal.Decimal{
+		"BNBUSDT": decimal.RequireFromString("21"),
+		"BNBBTC":  decimal.RequireFromString("0.0021"),
+	}
+	var ids []int64
+	for _, order := range KeepBest(orders, 1, mids) {
+		ids = append(ids, order.OrderID)
+	}
+	assert.ElementsMatch([]int64{3, 1}, ids)
+	assert.Empty(KeepBest(orders, 3, mids))
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// PanicReport define result of panic command for an account
+type PanicReport struct {
+	Clean    bool           `json:"clean"`
+	Attempts int            `json:"attempts"`
+	Handled  map[string]int `json:"handled"`
+	// Skipped are steps of margin or futures accounts which are not enabled
+	Skipped []string          `json:"skipped,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// panicStep define one step of panic command, run returns the number of
+// items handled, zero means nothing is left to do
+type panicStep struct {
+	name string
+	run  func(account *Account) (int, error)
+}
+
+func cancelSpotOrders(account *Account) (int, error) {
+	orders, err := account.ListOpenOrders("")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var orderSymbols []string
+	for _, order := range orders {
+		if !StrContains(orderSymbols, order.Symbol) {
+			orderSymbols = append(orderSymbols, order.Symbol)
+		}
+	}
+	for _, symbol := range orderSymbols {
+		err := account.CancelOpenOrders(symbol)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return len(orders), nil
+}
+
+func cancelMarginOrders(account *Account) (int, error) {
+	orders, err := account.ListMarginOpenOrders("")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	for _, order := range orders {
+		err := account.CancelMarginOrder(order.Symbol, order.OrderID)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return len(orders), nil
+}
+
+func cancelFuturesOrders(account *Account) (int, error) {
+	orders, err := account.ListFuturesOpenOrders("")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var orderSymbols []string
+	for _, order := range orders {
+		if !StrContains(orderSymbols, order.Symbol) {
+			orderSymbols = append(orderSymbols, order.Symbol)
+		}
+	}
+	for _, symbol := range orderSymbols {
+		err := account.CancelFuturesOpenOrders(symbol)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return len(orders), nil
+}
+
+func cancelDeliveryOrders(account *Account) (int, error) {
+	orders, err := account.ListDeliveryOpenOrders("")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var orderSymbols []string
+	for _, order := range orders {
+		if !StrContains(orderSymbols, order.Symbol) {
+			orderSymbols = append(orderSymbols, order.Symbol)
+		}
+	}
+	for _, symbol := range orderSymbols {
+		err := account.CancelDeliveryOpenOrders(symbol)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return len(orders), nil
+}
+
+func closeFuturesPositions(account *Account) (int, error) {
+	positions, err := account.ListFuturesPositions()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var n int
+	for _, position := range positions {
+		amount := decimal.RequireFromString(position.PositionAmt)
+		if amount.IsZero() {
+			continue
+		}
+		side := futures.SideTypeSell
+		if amount.IsNegative() {
+			side = futures.SideTypeBuy
+		}
+		_, err := account.CloseFuturesPosition(position.Symbol, side,
+			futures.PositionSideType(position.PositionSide), amount.Abs().String())
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func closeDeliveryPositions(account *Account) (int, error) {
+	positions, err := account.ListDeliveryPositions()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var n int
+	for _, position := range positions {
+		amount := decimal.RequireFromString(position.PositionAmt)
+		if amount.IsZero() {
+			continue
+		}
+		side := delivery.SideTypeSell
+		if amount.IsNegative() {
+			side = delivery.SideTypeBuy
+		}
+		_, err := account.CloseDeliveryPosition(position.Symbol, side,
+			delivery.PositionSideType(position.PositionSide), amount.Abs().String())
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// repayMarginLoans repay margin loans with free balance, it fails when loans
+// remain outstanding and nothing can be repaid for lack of free balance
+func repayMarginLoans(account *Account) (int, error) {
+	marginAccount, err := account.GetMarginAccount()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var n int
+	var outstanding []string
+	for _, asset := range marginAccount.UserAssets {
+		debt := decimal.RequireFromString(asset.Borrowed).Add(decimal.RequireFromString(asset.Interest))
+		free := decimal.RequireFromString(asset.Free)
+		if debt.GreaterThan(free) {
+			outstanding = append(outstanding, asset.Asset)
+		}
+		amount := decimal.Min(debt, free)
+		if !amount.IsPositive() {
+			continue
+		}
+		_, err := account.MarginRepay(asset.Asset, amount.String())
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		n++
+	}
+	if n == 0 && len(outstanding) > 0 {
+		return 0, errors.Errorf("loans of %s remain outstanding, free balance is not enough to repay",
+			strings.Join(outstanding, ", "))
+	}
+	return n, nil
+}
+
+// isNotEnabled check if err means margin account is not enabled. Invalid api
+// key, IP or permissions is not treated as not enabled, since flattening
+// would silently fail
+func isNotEnabled(err error) bool {
+	apiErr, ok := errors.Cause(err).(*common.APIError)
+	return ok && apiErr.Code == -3003
+}
+
+// runPanicSteps run steps on account until clean or deadline, steps of
+// accounts which are not enabled are skipped
+func runPanicSteps(account *Account, steps []panicStep, deadline time.Time) *PanicReport {
+	report := &PanicReport{Handled: make(map[string]int)}
+	for {
+		report.Attempts++
+		report.Errors = make(map[string]string)
+		clean := true
+		for _, step := range steps {
+			if StrContains(report.Skipped, step.name) {
+				continue
+			}
+			n, err := step.run(account)
+			if err != nil {
+				if step.name != "spot_orders" && isNotEnabled(err) {
+					report.Skipped = append(report.Skipped, step.name)
+					continue
+				}
+				report.Errors[step.name] = fmt.Sprintf("error: %s", err)
+				clean = false
+				continue
+			}
+			if n > 0 {
+				report.Handled[step.name] += n
+				clean = false
+			}
+		}
+		if clean || time.Now().After(deadline) {
+			report.Clean = clean
+			return report
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func panicAll(c *cli.Context) error {
+	timeout := c.Duration("timeout")
+	steps := []panicStep{
+		{"spot_orders", cancelSpotOrders},
+		{"margin_orders", cancelMarginOrders},
+		{"futures_orders", cancelFuturesOrders},
+		{"delivery_orders", cancelDeliveryOrders},
+	}
+	if c.Bool("close-positions") {
+		steps = append(steps,
+			panicStep{"futures_positions", closeFuturesPositions},
+			panicStep{"delivery_positions", closeDeliveryPositions})
+	}
+	if c.Bool("repay") {
+		steps = append(steps, panicStep{"margin_loans", repayMarginLoans})
+	}
+	skip := c.StringSlice("skip")
+	var selectedSteps []panicStep
+	for _, step := range steps {
+		if !StrContains(skip, step.name) {
+			selectedSteps = append(selectedSteps, step)
+		}
+	}
+	steps = selectedSteps
+	accounts := findAccounts(name)
+	if err := confirm("flatten risk on %d accounts?", len(accounts)); err != nil {
+		return errors.Trace(err)
+	}
+
+	// accounts run concurrently against one deadline, so a failing account
+	// does not delay the others
+	deadline := time.Now().Add(timeout)
+	reports := make(map[string]*PanicReport)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		wg.Add(1)
+		go func(account *Account) {
+			defer wg.Done()
+			report := runPanicSteps(account, steps, deadline)
+			mu.Lock()
+			reports[account.Name] = report
+			mu.Unlock()
+		}(account)
+	}
+	wg.Wait()
+	return print(reports)
+}
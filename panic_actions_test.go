@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPanicSteps(t *testing.T) {
+	assert := assert.New(t)
+	var ordersLeft = 2
+	steps := []panicStep{
+		{"spot_orders", func(account *Account) (int, error) {
+			n := ordersLeft
+			ordersLeft = 0
+			return n, nil
+		}},
+		{"margin_orders", func(account *Account) (int, error) {
+			return 0, errors.Trace(&common.APIError{Code: -3003, Message: "Margin account does not exist."})
+		}},
+	}
+	report := runPanicSteps(nil, steps, time.Now().Add(time.Minute))
+	assert.True(report.Clean)
+	assert.Equal(2, report.Attempts)
+	assert.Equal(2, report.Handled["spot_orders"])
+	assert.Equal([]string{"margin_orders"}, report.Skipped)
+
+	steps = []panicStep{
+		{"futures_orders", func(account *Account) (int, error) {
+			return 0, errors.Trace(&common.APIError{Code: -2015, Message: "Invalid API-key, IP, or permissions for action."})
+		}},
+	}
+	report = runPanicSteps(nil, steps, time.Now())
+	assert.False(report.Clean)
+	assert.Empty(report.Skipped)
+	assert.Contains(report.Errors, "futures_orders")
+
+	steps = []panicStep{
+		{"margin_loans", func(account *Account) (int, error) {
+			return 0, errors.New("loans of BTC remain outstanding")
+		}},
+	}
+	report = runPanicSteps(nil, steps, time.Now())
+	assert.False(report.Clean)
+	assert.Equal(1, report.Attempts)
+	assert.Contains(report.Errors, "margin_loans")
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// OrderPlan define a computed order request of account
+type OrderPlan struct {
+	Account  string `json:"account"`
+	Symbol   string `json:"symbol"`
+	Side     string `json:"side"`
+	Quantity string `json:"quantity"`
+	Price    string `json:"price"`
+	Notional string `json:"notional"`
+	Asset    string `json:"asset"`
+	// Balance and MarketPrice are empty for absolute quantity
+	Balance     string `json:"balance,omitempty"`
+	MarketPrice string `json:"market_price,omitempty"`
+	// PriceExpr is the relative price expression which Price is resolved from
+	PriceExpr string `json:"price_expr,omitempty"`
+	// Sizing describes how quantity is computed, empty for absolute quantity
+	Sizing string `json:"sizing,omitempty"`
+	// ClientOrderID is generated from --tag, orders already created with
+	// it are not created again
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// OrderResult define created order of plan and how its quantity is computed
+type OrderResult struct {
+	Order    interface{} `json:"order"`
+	Quantity string      `json:"quantity"`
+	Price    string      `json:"price"`
+	Sizing   string      `json:"sizing,omitempty"`
+}
+
+// planOrder compute order request of account without sending it
+func (account *Account) planOrder(symbol, side, quantity, quoteAmount, price, tag string,
+	accountBalances map[string]map[string]binance.Balance) (*OrderPlan, error) {
+	p, err := decimal.NewFromString(price)
+	if err != nil || !p.IsPositive() {
+		return nil, errors.Errorf("invalid price %q of LIMIT order", price)
+	}
+	err = account.loadSymbols()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found", symbol)
+	}
+	newQuantity, sizing, err := account.sizeOrder(symbol, side, quantity, quoteAmount, price, accountBalances)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	q, err := decimal.NewFromString(newQuantity)
+	if err != nil || !q.IsPositive() {
+		return nil, errors.Errorf("invalid quantity %q", newQuantity)
+	}
+	asset := info.QuoteAsset
+	if side == "SELL" {
+		asset = info.BaseAsset
+	}
+	plan := &OrderPlan{
+		Account:       account.Name,
+		Symbol:        symbol,
+		Side:          side,
+		Quantity:      newQuantity,
+		Price:         price,
+		Notional:      q.Mul(p).String(),
+		Asset:         asset,
+		Sizing:        sizing,
+		ClientOrderID: ClientOrderID(tag, account.Name, symbol, side),
+	}
+	// balance and market price are only recorded for drift check of quantity
+	// sized from them, absolute quantity does not depend on them
+	if sizing == "" {
+		return plan, nil
+	}
+	balance, err := account.freeBalance(asset, accountBalances)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	marketPrice, err := account.marketPrice(symbol)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	plan.Balance = balance.String()
+	plan.MarketPrice = marketPrice
+	return plan, nil
+}
+
+// marketPrice return latest price of symbol
+func (account *Account) marketPrice(symbol string) (string, error) {
+	prices, err := account.ListPrices(symbol)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	for _, p := range prices {
+		if p.Symbol == symbol {
+			return p.Price, nil
+		}
+	}
+	return "", errors.Errorf("price of %s not found", symbol)
+}
+
+// resolvePrice resolve relative price expression against book ticker, latest
+// price or average price of symbol, absolute price is returned as is
+func (account *Account) resolvePrice(symbol, expr string) (string, error) {
+	ref, shift := PriceReference(expr)
+	if ref == "" {
+		return expr, nil
+	}
+	err := account.loadSymbols()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	info, ok := symbols[symbol]
+	if !ok {
+		return "", errors.Errorf("symbol %s not found", symbol)
+	}
+	priceFilter := info.PriceFilter()
+	if priceFilter == nil {
+		return "", errors.Errorf("price filter of %s not found", symbol)
+	}
+	var refPrice string
+	switch ref {
+	case "bid", "ask":
+		tickers, err := account.ListBookTickers(symbol)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		for _, ticker := range tickers {
+			if ticker.Symbol != symbol {
+				continue
+			}
+			refPrice = ticker.BidPrice
+			if ref == "ask" {
+				refPrice = ticker.AskPrice
+			}
+		}
+		if refPrice == "" {
+			return "", errors.Errorf("book ticker of %s not found", symbol)
+		}
+	case "mid":
+		mid, err := account.MidPrice(symbol)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		refPrice = mid.String()
+	case "last":
+		refPrice, err = account.marketPrice(symbol)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+	case "avg":
+		refPrice, err = account.AveragePrice(symbol)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	price, err := ApplyPriceShift(decimal.RequireFromString(refPrice), shift, priceFilter.TickSize)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if !decimal.RequireFromString(price).IsPositive() {
+		return "", errors.Errorf("invalid price %s resolved from %s %s", price, ref, refPrice)
+	}
+	return price, nil
+}
+
+// loadPlans load order plans printed by create-order --plan, entries which
+// are not order plans, like errors of failed accounts, are rejected
+func loadPlans(filePath string) (map[string]*OrderPlan, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	plans := make(map[string]*OrderPlan)
+	for name, v := range raw {
+		plan := new(OrderPlan)
+		if err := json.Unmarshal(v, plan); err != nil || plan.Symbol == "" || plan.Quantity == "" || plan.Price == "" {
+			return nil, errors.Errorf("invalid plan of account %s: %s", name, v)
+		}
+		plans[name] = plan
+	}
+	return plans, nil
+}
+
+func applyPlan(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("plan file is required")
+	}
+	plans, err := loadPlans(c.Args().First())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+	tolerance := StrToPct(c.String("tolerance"))
+	isTest := c.Bool("test")
+
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			plan, ok := plans[account.Name]
+			if !ok {
+				return nil, errors.Errorf("plan of account %s not found", account.Name)
+			}
+			if err := config.CheckSymbol(plan.Symbol); err != nil {
+				return nil, errors.Trace(err)
+			}
+			// plan of absolute quantity has no balance and price to drift from
+			if plan.Balance == "" || plan.MarketPrice == "" {
+				return plan, nil
+			}
+			balance, err := account.freeBalance(plan.Asset, accountBalances)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if Drifted(plan.Balance, balance.String(), tolerance) {
+				return nil, errors.Errorf("balance of %s drifted from %s to %s", plan.Asset, plan.Balance, balance)
+			}
+			marketPrice, err := account.marketPrice(plan.Symbol)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if Drifted(plan.MarketPrice, marketPrice, tolerance) {
+				return nil, errors.Errorf("price of %s drifted from %s to %s", plan.Symbol, plan.MarketPrice, marketPrice)
+			}
+			return plan, nil
+		})
+	return createPlannedOrders(config, results, isTest)
+}
+
+// createPlannedOrders create orders of plans after checking limits and
+// confirmation, results of failed accounts are reported as is
+func createPlannedOrders(config *Config, plans map[string]interface{}, isTest bool) error {
+	var numOrders int
+	total := decimal.Decimal{}
+	for name, res := range plans {
+		plan, ok := res.(*OrderPlan)
+		if !ok {
+			continue
+		}
+		notional := decimal.RequireFromString(plan.Notional)
+		if err := config.CheckNotional(name, notional); err != nil {
+			return errors.Trace(err)
+		}
+		total = total.Add(notional)
+		numOrders++
+	}
+	if err := config.CheckAccounts(numOrders); err != nil {
+		return errors.Trace(err)
+	}
+	if !isTest && numOrders > 0 {
+		if err := confirm("create orders on %d accounts with total notional %s?", numOrders, total); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			plan, ok := plans[account.Name].(*OrderPlan)
+			if !ok {
+				return nil, resultError(plans[account.Name])
+			}
+			res, err := account.createPlannedOrder(plan, isTest)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return &OrderResult{
+				Order:    res,
+				Quantity: plan.Quantity,
+				Price:    plan.Price,
+				Sizing:   plan.Sizing,
+			}, nil
+		})
+}
+
+// createPlannedOrder create order of plan, open order already created with
+// the client order id of plan is returned instead of creating it again. A
+// finished order with the id does not prevent creating a new one
+func (account *Account) createPlannedOrder(plan *OrderPlan, isTest bool) (interface{}, error) {
+	if isTest {
+		err := account.TestCreateOrder(plan.Symbol, plan.Side, plan.Quantity, plan.Price, plan.ClientOrderID)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return "ok", nil
+	}
+	if plan.ClientOrderID != "" {
+		order, err := account.GetOrder(plan.Symbol, 0, plan.ClientOrderID)
+		if err == nil && !isOrderFinished(order) {
+			return order.OrderID, nil
+		}
+		if err != nil && !isOrderNotExist(err) {
+			return nil, errors.Trace(err)
+		}
+	}
+	res, err := account.CreateOrder(plan.Symbol, plan.Side, plan.Quantity, plan.Price, plan.ClientOrderID)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res.OrderID, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanOrderInvalidPrice(t *testing.T) {
+	assert := assert.New(t)
+	account := &Account{Name: "a1"}
+	for _, price := range []string{"", "abc", "0", "-1"} {
+		_, err := account.planOrder("BNBUSDT", "BUY", "1", "", price, "", nil)
+		assert.Error(err, price)
+	}
+}
+
+func TestLoadPlans(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "plan")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "plan.json")
+
+	tests := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{
+			name: "test with order plans",
+			data: `{"a1": {"symbol": "BNBUSDT", "side": "BUY", "quantity": "1", "price": "20"}}`,
+		},
+		{
+			name: "test with error of failed account",
+			data: `{"a1": {"symbol": "BNBUSDT", "side": "BUY", "quantity": "1", "price": "20"}, "a2": "error: timeout"}`,
+			err:  true,
+		},
+		{
+			name: "test with incomplete plan",
+			data: `{"a1": {"symbol": "BNBUSDT", "side": "BUY"}}`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(ioutil.WriteFile(filePath, []byte(tt.data), 0600))
+			plans, err := loadPlans(filePath)
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("BNBUSDT", plans["a1"].Symbol)
+		})
+	}
+}
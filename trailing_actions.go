@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// TrailingStop define persisted state of a client side trailing stop
+type TrailingStop struct {
+	Symbol   string `json:"symbol"`
+	Quantity string `json:"quantity"`
+	// Type is order type when triggered, LimitOffset is offset of price of
+	// LIMIT order below best bid
+	Type          string `json:"type"`
+	LimitOffset   string `json:"limit_offset,omitempty"`
+	Callback      string `json:"callback"`
+	Activation    string `json:"activation,omitempty"`
+	Activated     bool   `json:"activated"`
+	HighWaterMark string `json:"high_water_mark,omitempty"`
+	Triggered     bool   `json:"triggered"`
+	TriggerPrice  string `json:"trigger_price,omitempty"`
+	// Sold is order id of the sell of each account after trigger, accounts
+	// not in it are sold again on restart
+	Sold map[string]int64 `json:"sold,omitempty"`
+}
+
+// Resume check flags given on resume against state, a flag which differs
+// from state is rejected, state without it takes it from flags
+func (t *TrailingStop) Resume(flags *TrailingStop) error {
+	for _, f := range []struct {
+		name  string
+		state *string
+		flag  string
+	}{
+		{"symbol", &t.Symbol, flags.Symbol},
+		{"quantity", &t.Quantity, flags.Quantity},
+		{"type", &t.Type, flags.Type},
+		{"limit offset", &t.LimitOffset, flags.LimitOffset},
+		{"callback", &t.Callback, flags.Callback},
+		{"activation", &t.Activation, flags.Activation},
+	} {
+		if f.flag == "" || f.flag == *f.state {
+			continue
+		}
+		if *f.state != "" {
+			return errors.Errorf("%s %s differs from %s of trailing stop in state", f.name, f.flag, *f.state)
+		}
+		*f.state = f.flag
+	}
+	return nil
+}
+
+// pendingAccounts return names of accounts which are not sold yet
+func (t *TrailingStop) pendingAccounts(accounts map[string]*Account) []string {
+	var names []string
+	for name := range accounts {
+		if _, ok := t.Sold[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Update track best price since activation, it returns whether the state is
+// changed and whether price retraces from the best price by callback ratio
+func (t *TrailingStop) Update(price decimal.Decimal) (bool, bool) {
+	if t.Triggered {
+		return false, true
+	}
+	changed := false
+	if !t.Activated {
+		if t.Activation != "" && price.LessThan(decimal.RequireFromString(t.Activation)) {
+			return false, false
+		}
+		t.Activated = true
+		changed = true
+	}
+	if t.HighWaterMark == "" || price.GreaterThan(decimal.RequireFromString(t.HighWaterMark)) {
+		t.HighWaterMark = price.String()
+		changed = true
+	}
+	high := decimal.RequireFromString(t.HighWaterMark)
+	stop := high.Mul(decimal.NewFromInt(1).Sub(decimal.RequireFromString(t.Callback)))
+	if price.LessThanOrEqual(stop) {
+		t.Triggered = true
+		t.TriggerPrice = price.String()
+		return true, true
+	}
+	return changed, false
+}
+
+func loadTrailingStop(filePath string) (*TrailingStop, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	t := new(TrailingStop)
+	err = json.Unmarshal(data, t)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return t, nil
+}
+
+// waitTrailingStop watch ticker stream until the trailing stop triggers, it
+// returns false if interrupted by signal
+func waitTrailingStop(t *TrailingStop, statePath string) (bool, error) {
+	var mu sync.Mutex
+	triggerC := make(chan struct{}, 1)
+	handler := func(event *binance.WsMarketStatEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		changed, triggered := t.Update(decimal.RequireFromString(event.LastPrice))
+		if changed {
+			if err := writeJSONFile(statePath, t); err != nil {
+				log.Printf("failed to save trailing stop: %s", err)
+			}
+			log.Printf("%s: last price %s, high water mark %s", t.Symbol, event.LastPrice, t.HighWaterMark)
+		}
+		if triggered {
+			select {
+			case triggerC <- struct{}{}:
+			default:
+			}
+		}
+	}
+	errHandler := func(err error) {
+		log.Printf("ticker stream error: %s", err)
+	}
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGTERM)
+	for {
+		doneC, stopC, err := binance.WsMarketStatServe(t.Symbol, handler, errHandler)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		select {
+		case <-triggerC:
+			close(stopC)
+			return true, nil
+		case <-sigC:
+			close(stopC)
+			return false, nil
+		case <-doneC:
+			log.Printf("ticker stream closed, reconnecting")
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func trailingStop(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+	symbol := c.String("symbol")
+	statePath := c.String("state")
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if statePath == "" {
+		statePath = "trailing-" + symbol + ".json"
+	}
+	if err := config.CheckSymbol(symbol); err != nil {
+		return errors.Trace(err)
+	}
+	// flags not set explicitly are taken from state on resume
+	flags := &TrailingStop{
+		Symbol:     symbol,
+		Quantity:   c.String("quantity"),
+		Activation: c.String("activation"),
+	}
+	if c.IsSet("type") {
+		flags.Type = c.String("type")
+	}
+	if c.IsSet("limit-offset") {
+		flags.LimitOffset = decimal.NewFromFloat(StrToPct(c.String("limit-offset"))).String()
+	}
+	if c.IsSet("callback") {
+		flags.Callback = decimal.NewFromFloat(StrToPct(c.String("callback"))).String()
+	}
+	t, err := loadTrailingStop(statePath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if t == nil {
+		t = &TrailingStop{}
+	}
+	if err := t.Resume(flags); err != nil {
+		return errors.Trace(err)
+	}
+	if t.Type == "" {
+		t.Type = c.String("type")
+	}
+	if t.LimitOffset == "" {
+		t.LimitOffset = decimal.NewFromFloat(StrToPct(c.String("limit-offset"))).String()
+	}
+	if t.Quantity == "" {
+		return errors.New("quantity is required")
+	}
+	if t.Type != "market" && t.Type != "limit" {
+		return errors.Errorf("invalid order type %s", t.Type)
+	}
+	if callback, err := decimal.NewFromString(t.Callback); err != nil ||
+		!callback.IsPositive() || !callback.LessThan(decimal.NewFromInt(1)) {
+		return errors.New("callback must be between 0 and 100%")
+	}
+	quantity := t.Quantity
+	orderType := t.Type
+	limitOffset := decimal.RequireFromString(t.LimitOffset)
+	accounts := findAccounts(name)
+	if err := config.CheckAccounts(len(accounts)); err != nil {
+		return errors.Trace(err)
+	}
+	pending := t.pendingAccounts(accounts)
+	if len(pending) == 0 {
+		return errors.Errorf("trailing stop in %s is already triggered at %s and sold on all accounts", statePath, t.TriggerPrice)
+	}
+	if t.Triggered {
+		err = confirm("trailing stop of %s is triggered at %s, sell %s on %d pending accounts?",
+			symbol, t.TriggerPrice, quantity, len(pending))
+	} else {
+		err = confirm("arm trailing stop selling %s %s on %d accounts with callback %s?",
+			quantity, symbol, len(pending), t.Callback)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := writeJSONFile(statePath, t); err != nil {
+		return errors.Trace(err)
+	}
+
+	if !t.Triggered {
+		triggered, err := waitTrailingStop(t, statePath)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !triggered {
+			return print(t)
+		}
+		log.Printf("%s: trailing stop triggered at %s, high water mark %s", symbol, t.TriggerPrice, t.HighWaterMark)
+	}
+
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			if orderID, ok := t.Sold[account.Name]; ok {
+				return orderID, nil
+			}
+			orderID, err := account.sellTrailingStop(t, quantity, orderType, limitOffset, config, accountBalances)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if t.Sold == nil {
+				t.Sold = make(map[string]int64)
+			}
+			t.Sold[account.Name] = orderID
+			if err := writeJSONFile(statePath, t); err != nil {
+				return nil, errors.Trace(err)
+			}
+			return orderID, nil
+		})
+}
+
+// sellTrailingStop sell quantity of account after trigger, the client order
+// id is derived from trigger so an order sent before restart is not sent again
+func (account *Account) sellTrailingStop(t *TrailingStop, quantity, orderType string, limitOffset decimal.Decimal,
+	config *Config, accountBalances map[string]map[string]binance.Balance) (int64, error) {
+	symbol := t.Symbol
+	clientOrderID := ClientOrderID("trailing", account.Name, symbol, t.TriggerPrice)
+	order, err := account.GetOrder(symbol, 0, clientOrderID)
+	if err == nil {
+		return order.OrderID, nil
+	}
+	if !isOrderNotExist(err) {
+		return 0, errors.Trace(err)
+	}
+	newQuantity, err := account.orderQuantity(symbol, "SELL", quantity, t.TriggerPrice, accountBalances)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	notional := decimal.RequireFromString(newQuantity).Mul(decimal.RequireFromString(t.TriggerPrice))
+	if err := config.CheckNotional(account.Name, notional); err != nil {
+		return 0, errors.Trace(err)
+	}
+	if orderType == "market" {
+		res, err := account.CreateMarketOrder(symbol, "SELL", newQuantity, clientOrderID)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		return res.OrderID, nil
+	}
+	// aggressive LIMIT order below best bid
+	if err := account.loadSymbols(); err != nil {
+		return 0, errors.Trace(err)
+	}
+	info := symbols[symbol]
+	priceFilter := info.PriceFilter()
+	if priceFilter == nil {
+		return 0, errors.Errorf("price filter of %s not found", symbol)
+	}
+	tickers, err := account.ListBookTickers(symbol)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(tickers) == 0 {
+		return 0, errors.Errorf("book ticker of %s not found", symbol)
+	}
+	bid := decimal.RequireFromString(tickers[0].BidPrice)
+	price := RoundToTickSize(bid.Mul(decimal.NewFromInt(1).Sub(limitOffset)).String(), priceFilter.TickSize)
+	res, err := account.CreateOrder(symbol, "SELL", newQuantity, price, clientOrderID)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return res.OrderID, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailingStopUpdate(t *testing.T) {
+	assert := assert.New(t)
+	d := decimal.RequireFromString
+	stop := &TrailingStop{Symbol: "BNBUSDT", Callback: "0.02", Activation: "20"}
+
+	changed, triggered := stop.Update(d("19"))
+	assert.False(changed)
+	assert.False(triggered)
+	assert.False(stop.Activated)
+
+	changed, triggered = stop.Update(d("20"))
+	assert.True(changed)
+	assert.False(triggered)
+	assert.True(stop.Activated)
+
+	changed, triggered = stop.Update(d("25"))
+	assert.True(changed)
+	assert.False(triggered)
+	assert.Equal("25", stop.HighWaterMark)
+
+	changed, triggered = stop.Update(d("24.6"))
+	assert.False(changed)
+	assert.False(triggered)
+
+	changed, triggered = stop.Update(d("24.5"))
+	assert.True(changed)
+	assert.True(triggered)
+	assert.Equal("24.5", stop.TriggerPrice)
+
+	_, triggered = stop.Update(d("30"))
+	assert.True(triggered)
+	assert.Equal("25", stop.HighWaterMark)
+}
+
+func TestTrailingStopResume(t *testing.T) {
+	assert := assert.New(t)
+	stop := &TrailingStop{}
+	assert.NoError(stop.Resume(&TrailingStop{Symbol: "BNBUSDT", Quantity: "50%", Type: "market", Callback: "0.02"}))
+	assert.Equal("50%", stop.Quantity)
+
+	// flags not set are taken from state
+	assert.NoError(stop.Resume(&TrailingStop{Symbol: "BNBUSDT"}))
+	assert.NoError(stop.Resume(&TrailingStop{Symbol: "BNBUSDT", Quantity: "50%", Callback: "0.02"}))
+	assert.Equal("market", stop.Type)
+
+	assert.Error(stop.Resume(&TrailingStop{Symbol: "BTCUSDT"}))
+	assert.Error(stop.Resume(&TrailingStop{Symbol: "BNBUSDT", Quantity: "10%"}))
+	assert.Error(stop.Resume(&TrailingStop{Symbol: "BNBUSDT", Type: "limit"}))
+	assert.Error(stop.Resume(&TrailingStop{Symbol: "BNBUSDT", Callback: "0.03"}))
+}
+
+func TestTrailingStopPendingAccounts(t *testing.T) {
+	assert := assert.New(t)
+	accounts := map[string]*Account{"a": nil, "b": nil, "c": nil}
+	stop := &TrailingStop{}
+	assert.Equal([]string{"a", "b", "c"}, stop.pendingAccounts(accounts))
+	stop.Sold = map[string]int64{"b": 1}
+	assert.Equal([]string{"a", "c"}, stop.pendingAccounts(accounts))
+	stop.Sold["a"], stop.Sold["c"] = 2, 3
+	assert.Empty(stop.pendingAccounts(accounts))
+}
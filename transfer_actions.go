@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/juju/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// wallets of account which asset is transferred between
+const (
+	walletSpot     = "spot"
+	walletMargin   = "margin"
+	walletFutures  = "futures"
+	walletDelivery = "delivery"
+)
+
+// transfer types of COIN-M futures which are not defined by the sdk
+const (
+	futuresTransferTypeToDelivery   binance.FuturesTransferType = 3
+	futuresTransferTypeFromDelivery binance.FuturesTransferType = 4
+)
+
+// Transfer define a transfer of asset between wallets of account
+type Transfer struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+	// Available is the transferable amount of source wallet, it is empty
+	// when not needed by absolute amount
+	Available string `json:"available,omitempty"`
+	TranID    int64  `json:"tran_id,omitempty"`
+}
+
+// TransferAmount resolve absolute amount, percent of available or all
+// available amount, amount is truncated to 8 decimals
+func TransferAmount(amount string, available decimal.Decimal) (string, error) {
+	var v decimal.Decimal
+	switch {
+	case amount == "all":
+		v = available
+	case strings.HasSuffix(amount, "%"):
+		v = available.Mul(decimal.NewFromFloat(StrToPct(amount)))
+	default:
+		var err error
+		v, err = decimal.NewFromString(amount)
+		if err != nil {
+			return "", errors.Errorf("invalid amount %s", amount)
+		}
+	}
+	v = v.Truncate(8)
+	if !v.IsPositive() {
+		return "", errors.New("nothing to transfer")
+	}
+	return v.String(), nil
+}
+
+// transferable return amount of asset transferable out of wallet
+func (account *Account) transferable(wallet, asset string,
+	accountBalances map[string]map[string]binance.Balance) (decimal.Decimal, error) {
+	switch wallet {
+	case walletSpot:
+		return account.freeBalance(asset, accountBalances)
+	case walletMargin:
+		amount, err := account.GetMaxTransferable(asset)
+		if err != nil {
+			return decimal.Decimal{}, errors.Trace(err)
+		}
+		return decimal.RequireFromString(amount), nil
+	case walletFutures:
+		balances, err := account.ListFuturesBalances()
+		if err != nil {
+			return decimal.Decimal{}, errors.Trace(err)
+		}
+		for _, balance := range balances {
+			if balance.Asset == asset {
+				return decimal.RequireFromString(balance.MaxWithdrawAmount), nil
+			}
+		}
+		return decimal.Decimal{}, errors.Errorf("futures balance %s not found", asset)
+	}
+	// the sdk does not provide balance of COIN-M futures account
+	return decimal.Decimal{}, errors.Errorf("transferable amount of %s wallet is unknown, use absolute amount", wallet)
+}
+
+// planTransfer compute transfer of account, absolute amount out of margin is
+// bounded by max transferable
+func (account *Account) planTransfer(from, to, asset, amount string,
+	accountBalances map[string]map[string]binance.Balance) (*Transfer, error) {
+	t := &Transfer{From: from, To: to, Asset: asset, Amount: amount}
+	_, err := decimal.NewFromString(amount)
+	isAbsolute := err == nil
+	if isAbsolute && from != walletMargin {
+		t.Amount, err = TransferAmount(amount, decimal.Decimal{})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return t, nil
+	}
+	available, err := account.transferable(from, asset, accountBalances)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	t.Available = available.String()
+	t.Amount, err = TransferAmount(amount, available)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if decimal.RequireFromString(t.Amount).GreaterThan(available) {
+		return nil, errors.Errorf("amount %s is greater than transferable %s", t.Amount, available)
+	}
+	return t, nil
+}
+
+// sendTransfer send transfer with the service of its wallets
+func (account *Account) sendTransfer(t *Transfer) (int64, error) {
+	switch {
+	case t.To == walletMargin:
+		return account.MarginTransfer(t.Asset, t.Amount, binance.MarginTransferTypeToMargin)
+	case t.From == walletMargin:
+		return account.MarginTransfer(t.Asset, t.Amount, binance.MarginTransferTypeToMain)
+	case t.To == walletFutures:
+		return account.FuturesTransfer(t.Asset, t.Amount, binance.FuturesTransferTypeToFutures)
+	case t.From == walletFutures:
+		return account.FuturesTransfer(t.Asset, t.Amount, binance.FuturesTransferTypeToMain)
+	case t.To == walletDelivery:
+		return account.FuturesTransfer(t.Asset, t.Amount, futuresTransferTypeToDelivery)
+	case t.From == walletDelivery:
+		return account.FuturesTransfer(t.Asset, t.Amount, futuresTransferTypeFromDelivery)
+	}
+	return 0, errors.Errorf("transfer from %s to %s is not supported", t.From, t.To)
+}
+
+func transfer(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	accountBalances := config.AccountBalances()
+	from := strings.ToLower(c.String("from"))
+	to := strings.ToLower(c.String("to"))
+	asset := strings.ToUpper(c.String("asset"))
+	amount := c.String("amount")
+	if asset == "" || amount == "" {
+		return errors.New("asset and amount are required")
+	}
+	if (from == walletSpot) == (to == walletSpot) {
+		return errors.New("transfer must be from or to spot wallet")
+	}
+	for _, wallet := range []string{from, to} {
+		if !StrContains([]string{walletSpot, walletMargin, walletFutures, walletDelivery}, wallet) {
+			return errors.Errorf("invalid wallet %s", wallet)
+		}
+	}
+
+	results := accountsRun(
+		func(account *Account) (interface{}, error) {
+			t, err := account.planTransfer(from, to, asset, amount, accountBalances)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return t, nil
+		})
+	if c.Bool("dry-run") {
+		return print(results)
+	}
+	numAccounts := 0
+	total := decimal.Decimal{}
+	for _, res := range results {
+		if t, ok := res.(*Transfer); ok {
+			total = total.Add(decimal.RequireFromString(t.Amount))
+			numAccounts++
+		}
+	}
+	if err := config.CheckAccounts(numAccounts); err != nil {
+		return errors.Trace(err)
+	}
+	if numAccounts > 0 {
+		if err := confirm("transfer %s %s from %s to %s on %d accounts?", total, asset, from, to, numAccounts); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			t, ok := results[account.Name].(*Transfer)
+			if !ok {
+				return nil, resultError(results[account.Name])
+			}
+			tranID, err := account.sendTransfer(t)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			t.TranID = tranID
+			return t, nil
+		})
+}
+
+// listFuturesTransfers list transfers between spot and futures or delivery
+// wallet, the sdk provides no history of margin transfers
+func listFuturesTransfers(c *cli.Context) error {
+	asset := strings.ToUpper(c.String("asset"))
+	limit := c.Int("limit")
+	startTime := time.Now().Add(-c.Duration("since"))
+	if asset == "" {
+		return errors.New("asset is required")
+	}
+	return accountsDo(
+		func(account *Account) (interface{}, error) {
+			transfers, err := account.ListFuturesTransfers(asset, startTime.UnixNano()/int64(time.Millisecond), limit)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return transfers, nil
+		})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferAmount(t *testing.T) {
+	assert := assert.New(t)
+	available := decimal.RequireFromString("123.456789012")
+	tests := []struct {
+		name   string
+		amount string
+		expect string
+		err    bool
+	}{
+		{
+			name:   "test with absolute amount",
+			amount: "100",
+			expect: "100",
+		},
+		{
+			name:   "test with percent",
+			amount: "50%",
+			expect: "61.7283945",
+		},
+		{
+			name:   "test with all",
+			amount: "all",
+			expect: "123.45678901",
+		},
+		{
+			name:   "test with zero",
+			amount: "0",
+			err:    true,
+		},
+		{
+			name:   "test with invalid amount",
+			amount: "half",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := TransferAmount(tt.amount, available)
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.expect, amount)
+		})
+	}
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/juju/errors"
 	"github.com/shopspring/decimal"
 )
 
@@ -40,3 +50,338 @@ func StrToPct(s string) float64 {
 	v, _ := strconv.ParseFloat(s, 64)
 	return v
 }
+
+// Drifted check if actual deviates from expected by more than tolerance ratio
+func Drifted(expected, actual string, tolerance float64) bool {
+	expectedDec := decimal.RequireFromString(expected)
+	actualDec := decimal.RequireFromString(actual)
+	if expectedDec.IsZero() {
+		return !actualDec.IsZero()
+	}
+	diff := actualDec.Sub(expectedDec).Abs().Div(expectedDec.Abs())
+	return diff.GreaterThan(decimal.NewFromFloat(tolerance))
+}
+
+// clientOrderIDPattern is the charset and length of client order id accepted
+// by binance
+var clientOrderIDPattern = regexp.MustCompile(`^[\.A-Z\:/a-z0-9_-]{1,36}$`)
+
+// CheckClientOrderID check client order id against charset and length of
+// binance, empty id is allowed
+func CheckClientOrderID(id string) error {
+	if id != "" && !clientOrderIDPattern.MatchString(id) {
+		return errors.Errorf("invalid client order id %q, must match %s", id, clientOrderIDPattern)
+	}
+	return nil
+}
+
+// CheckTag check tag of generated client order ids against charset of
+// binance, tag longer than 27 chars is truncated by ClientOrderID
+func CheckTag(tag string) error {
+	if len(tag) > 27 {
+		tag = tag[:27]
+	}
+	return errors.Trace(CheckClientOrderID(tag))
+}
+
+// ClientOrderID generate deterministic client order id from tag and parts,
+// the tag is kept as prefix so orders can be filtered by it
+func ClientOrderID(tag string, parts ...string) string {
+	if tag == "" {
+		return ""
+	}
+	// client order id is limited to 36 chars, keep at least 8 chars of hash
+	if len(tag) > 27 {
+		tag = tag[:27]
+	}
+	sum := sha1.Sum([]byte(strings.Join(parts, "|")))
+	hash := hex.EncodeToString(sum[:])
+	return tag + "-" + hash[:35-len(tag)]
+}
+
+// ShiftValue apply shift expression to value, expr is an absolute value like
+// 21.5, a percentage like +0.5% or -1%, or a number of ticks like +3ticks
+func ShiftValue(value, expr, tickSize string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "+") && !strings.HasPrefix(expr, "-") {
+		v, err := decimal.NewFromString(expr)
+		if err != nil {
+			return "", errors.Errorf("invalid value %s", expr)
+		}
+		return v.String(), nil
+	}
+	valueDec := decimal.RequireFromString(value)
+	switch {
+	case strings.HasSuffix(expr, "%"):
+		pct, err := decimal.NewFromString(strings.TrimSuffix(expr, "%"))
+		if err != nil {
+			return "", errors.Errorf("invalid percentage %s", expr)
+		}
+		return valueDec.Add(valueDec.Mul(pct).Div(decimal.NewFromInt(100))).String(), nil
+	case strings.HasSuffix(expr, "ticks") || strings.HasSuffix(expr, "tick"):
+		n, err := decimal.NewFromString(strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(expr, "s"), "tick")))
+		if err != nil {
+			return "", errors.Errorf("invalid ticks %s", expr)
+		}
+		return valueDec.Add(n.Mul(decimal.RequireFromString(tickSize))).String(), nil
+	}
+	delta, err := decimal.NewFromString(expr)
+	if err != nil {
+		return "", errors.Errorf("invalid shift %s", expr)
+	}
+	return valueDec.Add(delta).String(), nil
+}
+
+// RoundToTickSize round price to nearest multiple of tick size
+func RoundToTickSize(price, tickSize string) string {
+	priceDec := decimal.RequireFromString(price)
+	tickSizeDec := decimal.RequireFromString(tickSize)
+	if tickSizeDec.IsZero() {
+		return priceDec.String()
+	}
+	return priceDec.Div(tickSizeDec).Round(0).Mul(tickSizeDec).String()
+}
+
+// priceReferences are names of reference prices of relative price expression
+var priceReferences = []string{"bid", "ask", "mid", "last", "avg"}
+
+// PriceReference split relative price expression "mid+3ticks" into reference
+// price name and shift, reference is empty for absolute price
+func PriceReference(expr string) (string, string) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	for _, ref := range priceReferences {
+		if strings.HasPrefix(expr, ref) {
+			return ref, strings.TrimSpace(strings.TrimPrefix(expr, ref))
+		}
+	}
+	return "", expr
+}
+
+// ApplyPriceShift shift reference price by "+0.1%", "-3ticks", "+0.5" or
+// "*0.98" and round it to tick size, empty shift keeps reference price
+func ApplyPriceShift(ref decimal.Decimal, shift, tickSize string) (string, error) {
+	price := ref.String()
+	switch {
+	case shift == "":
+	case strings.HasPrefix(shift, "*"):
+		factor, err := decimal.NewFromString(strings.TrimSpace(strings.TrimPrefix(shift, "*")))
+		if err != nil {
+			return "", errors.Errorf("invalid factor %s", shift)
+		}
+		price = ref.Mul(factor).String()
+	case strings.HasPrefix(shift, "+") || strings.HasPrefix(shift, "-"):
+		var err error
+		price, err = ShiftValue(price, shift, tickSize)
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+	default:
+		return "", errors.Errorf("invalid price shift %s", shift)
+	}
+	return RoundToTickSize(price, tickSize), nil
+}
+
+// AmendClientOrderID generate client order id of a replacement order, a
+// sequence number is appended to the original id so lineage is kept
+func AmendClientOrderID(clientOrderID string) string {
+	base := clientOrderID
+	seq := 1
+	if i := strings.LastIndex(clientOrderID, "."); i >= 0 {
+		if n, err := strconv.Atoi(clientOrderID[i+1:]); err == nil {
+			base = clientOrderID[:i]
+			seq = n + 1
+		}
+	}
+	suffix := "." + strconv.Itoa(seq)
+	if len(base)+len(suffix) > 36 {
+		base = base[:36-len(suffix)]
+	}
+	return base + suffix
+}
+
+// LadderPrices return count prices evenly distributed from from to to,
+// prices must be positive decimals and differ when count is more than one
+func LadderPrices(from, to string, count int) ([]decimal.Decimal, error) {
+	if count < 1 {
+		return nil, errors.Errorf("invalid count %d", count)
+	}
+	fromDec, err := decimal.NewFromString(from)
+	if err != nil || !fromDec.IsPositive() {
+		return nil, errors.Errorf("invalid price %s", from)
+	}
+	toDec, err := decimal.NewFromString(to)
+	if err != nil || !toDec.IsPositive() {
+		return nil, errors.Errorf("invalid price %s", to)
+	}
+	if count == 1 {
+		return []decimal.Decimal{fromDec}, nil
+	}
+	if fromDec.Equal(toDec) {
+		return nil, errors.Errorf("prices of %d levels from %s to %s are equal", count, from, to)
+	}
+	step := toDec.Sub(fromDec).Div(decimal.NewFromInt(int64(count - 1)))
+	prices := make([]decimal.Decimal, count)
+	for i := range prices {
+		prices[i] = fromDec.Add(step.Mul(decimal.NewFromInt(int64(i))))
+	}
+	return prices, nil
+}
+
+// RoundLadderPrices round prices of levels to tick size, levels rounded to
+// the same price are rejected since their orders would share a client order
+// id and replace each other
+func RoundLadderPrices(prices []decimal.Decimal, tickSize string) ([]string, error) {
+	rounded := make([]string, len(prices))
+	seen := make(map[string]int)
+	for i, p := range prices {
+		rounded[i] = RoundToTickSize(p.String(), tickSize)
+		if j, ok := seen[rounded[i]]; ok {
+			return nil, errors.Errorf("levels %d and %d are both rounded to price %s of tick size %s, use fewer levels or a wider range",
+				j+1, i+1, rounded[i], tickSize)
+		}
+		seen[rounded[i]] = i
+	}
+	return rounded, nil
+}
+
+// LadderWeights return count weights summing to 1, weighting is uniform,
+// linear (increasing by level) or geometric (multiplied by ratio by level)
+func LadderWeights(count int, weighting string, ratio float64) ([]decimal.Decimal, error) {
+	weights := make([]decimal.Decimal, count)
+	sum := decimal.Decimal{}
+	for i := range weights {
+		switch weighting {
+		case "", "uniform":
+			weights[i] = decimal.NewFromInt(1)
+		case "linear":
+			weights[i] = decimal.NewFromInt(int64(i + 1))
+		case "geometric":
+			weights[i] = decimal.NewFromFloat(ratio).Pow(decimal.NewFromInt(int64(i)))
+		default:
+			return nil, errors.Errorf("invalid weighting %s", weighting)
+		}
+		sum = sum.Add(weights[i])
+	}
+	for i := range weights {
+		weights[i] = weights[i].Div(sum)
+	}
+	return weights, nil
+}
+
+// EquityValue sum value of balances in quote asset, assets without a direct
+// pair with quote asset are ignored
+func EquityValue(balances map[string]decimal.Decimal, prices map[string]string, quote string) decimal.Decimal {
+	total := decimal.Decimal{}
+	for asset, amount := range balances {
+		if asset == quote {
+			total = total.Add(amount)
+		} else if p, ok := prices[asset+quote]; ok {
+			total = total.Add(amount.Mul(decimal.RequireFromString(p)))
+		} else if p, ok := prices[quote+asset]; ok && !decimal.RequireFromString(p).IsZero() {
+			total = total.Add(amount.Div(decimal.RequireFromString(p)))
+		}
+	}
+	return total
+}
+
+// SplitEven allocate total quantity between holdings so that holdings after
+// trade are as even as possible, BUY fills the lowest holdings first and
+// SELL takes from the highest ones
+func SplitEven(holdings map[string]decimal.Decimal, total decimal.Decimal, side string) map[string]decimal.Decimal {
+	sign := decimal.NewFromInt(1)
+	if side == "SELL" {
+		sign = decimal.NewFromInt(-1)
+	}
+	names := make([]string, 0, len(holdings))
+	for name := range holdings {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return holdings[names[i]].Mul(sign).LessThan(holdings[names[j]].Mul(sign))
+	})
+	// find level of the k lowest holdings which absorbs total
+	level := decimal.Decimal{}
+	sum := total
+	for k, name := range names {
+		sum = sum.Add(holdings[name].Mul(sign))
+		level = sum.Div(decimal.NewFromInt(int64(k + 1)))
+		if k+1 == len(names) || level.LessThanOrEqual(holdings[names[k+1]].Mul(sign)) {
+			break
+		}
+	}
+	allocations := make(map[string]decimal.Decimal)
+	for _, name := range names {
+		amount := level.Sub(holdings[name].Mul(sign))
+		if amount.IsNegative() {
+			amount = decimal.Decimal{}
+		}
+		allocations[name] = amount
+	}
+	return allocations
+}
+
+// writeJSONFile write v to a temp file and rename it, so an interrupted
+// write never corrupts the file
+func writeJSONFile(filePath string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tmpPath := filePath + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(os.Rename(tmpPath, filePath))
+}
+
+// writeCSVFile write header and rows to file in csv format
+func writeCSVFile(filePath string, header []string, rows [][]string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(f.Close())
+}
+
+// ParseDateRange parse start and end dates like 2020-12-31 in UTC, end date
+// is inclusive and capped at now. end defaults to now and start defaults to
+// days before end
+func ParseDateRange(start, end string, days int, now time.Time) (time.Time, time.Time, error) {
+	endTime := now
+	if end != "" {
+		t, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.Trace(err)
+		}
+		endTime = t.Add(24 * time.Hour)
+		if endTime.After(now) {
+			endTime = now
+		}
+	}
+	startTime := endTime.Add(-time.Duration(days) * 24 * time.Hour)
+	if start != "" {
+		t, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.Trace(err)
+		}
+		startTime = t
+	}
+	if !startTime.Before(endTime) {
+		return time.Time{}, time.Time{}, errors.New("start must be before end")
+	}
+	return startTime, endTime, nil
+}
+
+// formatMillis format time in milliseconds in UTC
+func formatMillis(t int64) string {
+	return time.Unix(0, t*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
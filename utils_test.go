@@ -1,8 +1,11 @@
 package main
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -123,3 +126,496 @@ func TestStrToPct(t *testing.T) {
 		})
 	}
 }
+
+func TestDrifted(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name      string
+		expected  string
+		actual    string
+		tolerance float64
+		expect    bool
+	}{
+		{
+			name:      "test within tolerance",
+			expected:  "100",
+			actual:    "100.5",
+			tolerance: 0.01,
+			expect:    false,
+		},
+		{
+			name:      "test beyond tolerance",
+			expected:  "100",
+			actual:    "98.9",
+			tolerance: 0.01,
+			expect:    true,
+		},
+		{
+			name:      "test with zero expected",
+			expected:  "0",
+			actual:    "0.0001",
+			tolerance: 0.01,
+			expect:    true,
+		},
+		{
+			name:      "test with zero tolerance",
+			expected:  "1.5",
+			actual:    "1.50",
+			tolerance: 0,
+			expect:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(tt.expect, Drifted(tt.expected, tt.actual, tt.tolerance))
+		})
+	}
+}
+
+func TestClientOrderID(t *testing.T) {
+	assert := assert.New(t)
+
+	id := ClientOrderID("grid1", "demo", "BNBUSDT", "BUY")
+	assert.Equal(id, ClientOrderID("grid1", "demo", "BNBUSDT", "BUY"))
+	assert.NotEqual(id, ClientOrderID("grid1", "demo", "BNBUSDT", "SELL"))
+	assert.True(strings.HasPrefix(id, "grid1-"))
+	assert.Len(id, 36)
+
+	long := ClientOrderID("a-very-long-tag-for-client-order-id", "demo")
+	assert.Len(long, 36)
+	assert.True(strings.HasPrefix(long, "a-very-long-tag-for-client-"))
+
+	assert.Equal("", ClientOrderID("", "demo", "BNBUSDT", "BUY"))
+}
+
+func TestCheckTag(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(CheckTag(""))
+	assert.NoError(CheckTag("mm1"))
+	assert.NoError(CheckTag("grid.v2:a/b_c-d"))
+	assert.NoError(CheckTag("a-very-long-tag-for-client-order-id"))
+	assert.Error(CheckTag("mm 1"))
+	assert.Error(CheckTag("mm#1"))
+	assert.Error(CheckTag("标签"))
+
+	assert.NoError(CheckClientOrderID(""))
+	assert.NoError(CheckClientOrderID(ClientOrderID("mm1", "demo", "BNBUSDT", "BUY")))
+	assert.Error(CheckClientOrderID(strings.Repeat("a", 37)))
+	assert.Error(CheckClientOrderID("mm,1"))
+}
+
+func TestShiftValue(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name     string
+		value    string
+		expr     string
+		tickSize string
+		expect   string
+	}{
+		{
+			name:     "test with absolute value",
+			value:    "20",
+			expr:     "21.5",
+			tickSize: "0.01",
+			expect:   "21.5",
+		},
+		{
+			name:     "test with positive percentage",
+			value:    "20",
+			expr:     "+0.5%",
+			tickSize: "0.01",
+			expect:   "20.1",
+		},
+		{
+			name:     "test with negative percentage",
+			value:    "20",
+			expr:     "-10%",
+			tickSize: "0.01",
+			expect:   "18",
+		},
+		{
+			name:     "test with ticks",
+			value:    "20",
+			expr:     "+3ticks",
+			tickSize: "0.01",
+			expect:   "20.03",
+		},
+		{
+			name:     "test with negative tick",
+			value:    "20",
+			expr:     "-1 tick",
+			tickSize: "0.0001",
+			expect:   "19.9999",
+		},
+		{
+			name:     "test with delta",
+			value:    "20",
+			expr:     "+0.5",
+			tickSize: "0.01",
+			expect:   "20.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := ShiftValue(tt.value, tt.expr, tt.tickSize)
+			assert.NoError(err)
+			assert.Equal(tt.expect, v)
+		})
+	}
+
+	_, err := ShiftValue("20", "+abc%", "0.01")
+	assert.Error(err)
+}
+
+func TestRoundToTickSize(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("20.12", RoundToTickSize("20.1234", "0.01"))
+	assert.Equal("20.13", RoundToTickSize("20.1251", "0.01"))
+	assert.Equal("20.5", RoundToTickSize("20.4", "0.5"))
+	assert.Equal("20.1234", RoundToTickSize("20.1234", "0"))
+}
+
+func TestAmendClientOrderID(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("mm1-abc.1", AmendClientOrderID("mm1-abc"))
+	assert.Equal("mm1-abc.2", AmendClientOrderID("mm1-abc.1"))
+	assert.Equal("web_x.y.1", AmendClientOrderID("web_x.y"))
+	id := AmendClientOrderID("mm1-0123456789012345678901234567890")
+	assert.Len(id, 36)
+	assert.Equal("mm1-012345678901234567890123456789.1", id)
+}
+
+func TestRoundLadderPrices(t *testing.T) {
+	assert := assert.New(t)
+	prices, err := LadderPrices("20", "21", 3)
+	assert.NoError(err)
+	rounded, err := RoundLadderPrices(prices, "0.1")
+	assert.NoError(err)
+	assert.Equal([]string{"20", "20.5", "21"}, rounded)
+	_, err = RoundLadderPrices(prices, "1")
+	assert.Error(err)
+}
+
+func TestLadderPrices(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		count  int
+		expect []string
+		err    bool
+	}{
+		{
+			name:   "test with ascending prices",
+			from:   "20",
+			to:     "25",
+			count:  6,
+			expect: []string{"20", "21", "22", "23", "24", "25"},
+		},
+		{
+			name:   "test with descending prices",
+			from:   "25",
+			to:     "24",
+			count:  3,
+			expect: []string{"25", "24.5", "24"},
+		},
+		{
+			name:   "test with one level",
+			from:   "20",
+			to:     "25",
+			count:  1,
+			expect: []string{"20"},
+		},
+		{
+			name:  "test with invalid from",
+			from:  "abc",
+			to:    "25",
+			count: 3,
+			err:   true,
+		},
+		{
+			name:  "test with empty to",
+			from:  "20",
+			to:    "",
+			count: 3,
+			err:   true,
+		},
+		{
+			name:  "test with non positive price",
+			from:  "0",
+			to:    "25",
+			count: 3,
+			err:   true,
+		},
+		{
+			name:  "test with equal prices",
+			from:  "20",
+			to:    "20",
+			count: 3,
+			err:   true,
+		},
+		{
+			name:  "test with zero count",
+			from:  "20",
+			to:    "25",
+			count: 0,
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices, err := LadderPrices(tt.from, tt.to, tt.count)
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			var l []string
+			for _, p := range prices {
+				l = append(l, p.String())
+			}
+			assert.Equal(tt.expect, l)
+		})
+	}
+}
+
+func TestLadderWeights(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name      string
+		weighting string
+		ratio     float64
+		expect    []string
+	}{
+		{
+			name:      "test with uniform",
+			weighting: "uniform",
+			expect:    []string{"0.25", "0.25", "0.25", "0.25"},
+		},
+		{
+			name:      "test with linear",
+			weighting: "linear",
+			expect:    []string{"0.1", "0.2", "0.3", "0.4"},
+		},
+		{
+			name:      "test with geometric",
+			weighting: "geometric",
+			ratio:     3,
+			expect:    []string{"0.025", "0.075", "0.225", "0.675"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights, err := LadderWeights(4, tt.weighting, tt.ratio)
+			assert.NoError(err)
+			var l []string
+			for _, w := range weights {
+				l = append(l, w.String())
+			}
+			assert.Equal(tt.expect, l)
+		})
+	}
+
+	_, err := LadderWeights(4, "random", 0)
+	assert.Error(err)
+}
+
+func TestEquityValue(t *testing.T) {
+	assert := assert.New(t)
+	balances := map[string]decimal.Decimal{
+		"USDT": decimal.RequireFromString("100"),
+		"BTC":  decimal.RequireFromString("0.5"),
+		"EUR":  decimal.RequireFromString("20"),
+		"FOO":  decimal.RequireFromString("1000"),
+	}
+	prices := map[string]string{
+		"BTCUSDT": "20000",
+		"USDTEUR": "0.8",
+	}
+	assert.Equal("10125", EquityValue(balances, prices, "USDT").String())
+}
+
+func TestSplitEven(t *testing.T) {
+	assert := assert.New(t)
+	holdings := map[string]decimal.Decimal{
+		"a": decimal.RequireFromString("1"),
+		"b": decimal.RequireFromString("3"),
+		"c": decimal.RequireFromString("6"),
+	}
+	tests := []struct {
+		name   string
+		total  string
+		side   string
+		expect map[string]string
+	}{
+		{
+			name:   "test buy fills lowest first",
+			total:  "4",
+			side:   "BUY",
+			expect: map[string]string{"a": "3", "b": "1", "c": "0"},
+		},
+		{
+			name:   "test buy beyond highest",
+			total:  "8",
+			side:   "BUY",
+			expect: map[string]string{"a": "5", "b": "3", "c": "0"},
+		},
+		{
+			name:   "test sell takes from highest",
+			total:  "4",
+			side:   "SELL",
+			expect: map[string]string{"a": "0", "b": "0.5", "c": "3.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocations := SplitEven(holdings, decimal.RequireFromString(tt.total), tt.side)
+			l := make(map[string]string)
+			for name, amount := range allocations {
+				l[name] = amount.String()
+			}
+			assert.Equal(tt.expect, l)
+		})
+	}
+}
+
+func TestApplyPriceShift(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name   string
+		expr   string
+		ref    string
+		expect string
+		err    bool
+	}{
+		{
+			name:   "test with absolute price",
+			expr:   "20.5",
+			expect: "20.5",
+		},
+		{
+			name:   "test with reference only",
+			expr:   "bid",
+			ref:    "bid",
+			expect: "100",
+		},
+		{
+			name:   "test with percent",
+			expr:   "ask-0.1%",
+			ref:    "ask",
+			expect: "99.9",
+		},
+		{
+			name:   "test with ticks",
+			expr:   "mid+3ticks",
+			ref:    "mid",
+			expect: "100.3",
+		},
+		{
+			name:   "test with factor",
+			expr:   "last*0.98",
+			ref:    "last",
+			expect: "98",
+		},
+		{
+			name:   "test with rounding",
+			expr:   "avg*0.99999",
+			ref:    "avg",
+			expect: "100",
+		},
+		{
+			name: "test with invalid shift",
+			expr: "bid/2",
+			ref:  "bid",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, shift := PriceReference(tt.expr)
+			assert.Equal(tt.ref, ref)
+			if ref == "" {
+				assert.Equal(tt.expect, shift)
+				return
+			}
+			price, err := ApplyPriceShift(decimal.NewFromInt(100), shift, "0.1")
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.expect, price)
+		})
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		start       string
+		end         string
+		expectStart time.Time
+		expectEnd   time.Time
+		err         bool
+	}{
+		{
+			name:        "test with default range",
+			expectStart: now.Add(-7 * 24 * time.Hour),
+			expectEnd:   now,
+		},
+		{
+			name:        "test with inclusive end",
+			start:       "2020-12-01",
+			end:         "2020-12-31",
+			expectStart: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+			expectEnd:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "test with one day",
+			start:       "2020-12-31",
+			end:         "2020-12-31",
+			expectStart: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC),
+			expectEnd:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "test with end of today capped at now",
+			end:         "2021-01-10",
+			expectStart: now.Add(-7 * 24 * time.Hour),
+			expectEnd:   now,
+		},
+		{
+			name:  "test with start after end",
+			start: "2021-01-02",
+			end:   "2021-01-01",
+			err:   true,
+		},
+		{
+			name: "test with invalid end",
+			end:  "2021/01/01",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseDateRange(tt.start, tt.end, 7, now)
+			if tt.err {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.expectStart, start)
+			assert.Equal(tt.expectEnd, end)
+		})
+	}
+}